@@ -0,0 +1,98 @@
+// Package auth authenticates the callers of the REST API and tells their
+// roles.
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Role is what a caller is allowed to do.
+type Role string
+
+const (
+	// RoleViewer reads components and executions.
+	RoleViewer Role = "viewer"
+	// RoleAuthor creates, updates and deletes components.
+	RoleAuthor Role = "author"
+	// RoleExecutor executes components and stops executions.
+	RoleExecutor Role = "executor"
+	// RoleAdmin manages executors and has every other role.
+	RoleAdmin Role = "admin"
+)
+
+// implied are the roles granted along with a role.
+var implied = map[Role][]Role{
+	RoleAuthor:   {RoleViewer},
+	RoleExecutor: {RoleViewer},
+	RoleAdmin:    {RoleViewer, RoleAuthor, RoleExecutor},
+}
+
+// ErrNoCredentials is returned by an authenticator when the request carries
+// no credentials it understands.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Identity is an authenticated caller.
+type Identity struct {
+	Name  string
+	Roles []Role
+}
+
+// HasRole reports whether the identity has role, directly or through a
+// role which implies it.
+func (identity *Identity) HasRole(role Role) bool {
+	for _, r := range identity.Roles {
+		if r == role {
+			return true
+		}
+		for _, i := range implied[r] {
+			if i == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Authenticator tells the identity of the caller of a request.
+type Authenticator interface {
+	// Authenticate returns ErrNoCredentials if req has no credentials for
+	// the authenticator, and another error if they are invalid.
+	Authenticate(req *http.Request) (*Identity, error)
+}
+
+// Chain tries its authenticators in order, the first one which finds
+// credentials decides.
+type Chain []Authenticator
+
+func (chain Chain) Authenticate(req *http.Request) (*Identity, error) {
+	for _, authenticator := range chain {
+		identity, err := authenticator.Authenticate(req)
+		if err == ErrNoCredentials {
+			continue
+		}
+		return identity, err
+	}
+	return nil, ErrNoCredentials
+}
+
+// bearerToken returns the token of the Authorization header of req.
+func bearerToken(req *http.Request) string {
+	header := req.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+func parseRoles(values []string) []Role {
+	roles := make([]Role, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			roles = append(roles, Role(value))
+		}
+	}
+	return roles
+}
@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksMinRefresh limits how often an unknown key id refreshes the keys.
+	jwksMinRefresh = time.Minute
+	// jwtLeeway is the clock skew allowed when checking exp and nbf.
+	jwtLeeway = time.Minute
+)
+
+var jwtAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+// JWTConfig configures the verification of OIDC id tokens or other JWTs
+// signed by RSA keys.
+type JWTConfig struct {
+	// JWKS is the url or the local file path of the JSON web key set.
+	JWKS     string
+	Issuer   string
+	Audience string
+	// RolesClaim is the claim holding the roles of the caller, either a
+	// list or a space separated string.
+	RolesClaim string
+	// NameClaim is the claim naming the caller, sub by default.
+	NameClaim string
+}
+
+// JWT authenticates bearer tokens which are JWTs signed by a key of a JSON
+// web key set.
+type JWT struct {
+	config JWTConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	refreshed time.Time
+}
+
+func NewJWT(config JWTConfig) (*JWT, error) {
+	if config.JWKS == "" {
+		return nil, errors.New("should specify jwks")
+	}
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+	if config.NameClaim == "" {
+		config.NameClaim = "sub"
+	}
+	verifier := &JWT{
+		config: config,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	if err := verifier.refresh(); err != nil {
+		return nil, err
+	}
+	return verifier, nil
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (verifier *JWT) readJWKS() ([]byte, error) {
+	source := verifier.config.JWKS
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return ioutil.ReadFile(strings.TrimPrefix(source, "file://"))
+	}
+	resp, err := verifier.client.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response code: %d", resp.StatusCode)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// refresh reloads the keys, it should be called with mu locked or before
+// the verifier is shared.
+func (verifier *JWT) refresh() error {
+	data, err := verifier.readJWKS()
+	if err != nil {
+		return errors.New("read jwks error: " + err.Error())
+	}
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return errors.New("unmarshal jwks error: " + err.Error())
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, key := range jwks.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return fmt.Errorf("decode modulus of key %s error: %s", key.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return fmt.Errorf("decode exponent of key %s error: %s", key.Kid, err)
+		}
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	verifier.keys = keys
+	verifier.refreshed = time.Now()
+	return nil
+}
+
+func (verifier *JWT) key(kid string) (*rsa.PublicKey, error) {
+	verifier.mu.Lock()
+	defer verifier.mu.Unlock()
+	if key, ok := verifier.keys[kid]; ok {
+		return key, nil
+	}
+	// The keys may have been rotated by the issuer.
+	if time.Since(verifier.refreshed) >= jwksMinRefresh {
+		if err := verifier.refresh(); err != nil {
+			return nil, err
+		}
+		if key, ok := verifier.keys[kid]; ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id %s", kid)
+}
+
+func (verifier *JWT) Authenticate(req *http.Request) (*Identity, error) {
+	token := bearerToken(req)
+	if strings.Count(token, ".") != 2 {
+		return nil, ErrNoCredentials
+	}
+	claims, err := verifier.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	identity := new(Identity)
+	identity.Name, _ = claims[verifier.config.NameClaim].(string)
+	switch roles := claims[verifier.config.RolesClaim].(type) {
+	case string:
+		identity.Roles = parseRoles(strings.Fields(roles))
+	case []interface{}:
+		values := make([]string, 0, len(roles))
+		for _, role := range roles {
+			if value, ok := role.(string); ok {
+				values = append(values, value)
+			}
+		}
+		identity.Roles = parseRoles(values)
+	}
+	return identity, nil
+}
+
+// Verify checks the signature, issuer, audience and lifetime of token and
+// returns its claims.
+func (verifier *JWT) Verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed jwt")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.New("decode jwt header error: " + err.Error())
+	}
+	hash, ok := jwtAlgorithms[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported jwt algorithm %s", header.Alg)
+	}
+	key, err := verifier.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("decode jwt signature error: " + err.Error())
+	}
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, hash, h.Sum(nil), signature); err != nil {
+		return nil, errors.New("invalid jwt signature")
+	}
+
+	claims := make(map[string]interface{})
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.New("decode jwt claims error: " + err.Error())
+	}
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.New("jwt has no exp claim")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(jwtLeeway)) {
+		return nil, errors.New("jwt expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("jwt not valid yet")
+	}
+	if verifier.config.Issuer != "" && claims["iss"] != verifier.config.Issuer {
+		return nil, errors.New("invalid jwt issuer")
+	}
+	if verifier.config.Audience != "" && !hasAudience(claims["aud"], verifier.config.Audience) {
+		return nil, errors.New("invalid jwt audience")
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+)
+
+// signingKey returns the RSA key the test tokens are signed with, it is
+// generated once as it is slow.
+func signingKey(t *testing.T) *rsa.PrivateKey {
+	testKeyOnce.Do(func() {
+		var err error
+		testKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("generate rsa key error: %s", err)
+		}
+	})
+	return testKey
+}
+
+// testJWKS returns a json web key set holding the public key of key as kid.
+func testJWKS(key *rsa.PrivateKey, kid string) []byte {
+	e := big.NewInt(int64(key.PublicKey.E)).Bytes()
+	data, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": kid,
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(e),
+		}},
+	})
+	return data
+}
+
+// signToken returns a RS256 jwt of claims signed by key.
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims error: %s", err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	h := crypto.SHA256.New()
+	h.Write([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h.Sum(nil))
+	if err != nil {
+		t.Fatalf("sign token error: %s", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// newTestJWT returns a verifier of the tokens signed by the test key as
+// key-1, whose key set is served by a local server.
+func newTestJWT(t *testing.T) (*JWT, func()) {
+	jwks := testJWKS(signingKey(t), "key-1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jwks)
+	}))
+	verifier, err := NewJWT(JWTConfig{
+		JWKS:     server.URL,
+		Issuer:   "https://issuer.example.com",
+		Audience: "containerops",
+	})
+	if err != nil {
+		server.Close()
+		t.Fatalf("NewJWT error: %s", err)
+	}
+	return verifier, server.Close
+}
+
+func validClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":   "https://issuer.example.com",
+		"aud":   "containerops",
+		"sub":   "alice",
+		"exp":   now.Add(time.Hour).Unix(),
+		"nbf":   now.Add(-time.Minute).Unix(),
+		"roles": []string{"author", "executor"},
+	}
+}
+
+func bearerRequest(token string) *http.Request {
+	req, _ := http.NewRequest("GET", "/v2/components", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestJWTValidToken(t *testing.T) {
+	verifier, stop := newTestJWT(t)
+	defer stop()
+
+	token := signToken(t, signingKey(t), "key-1", validClaims())
+	identity, err := verifier.Authenticate(bearerRequest(token))
+	if err != nil {
+		t.Fatalf("Authenticate error: %s", err)
+	}
+	if identity.Name != "alice" {
+		t.Errorf("got name %q, want alice", identity.Name)
+	}
+	want := []Role{RoleAuthor, RoleExecutor}
+	if !reflect.DeepEqual(identity.Roles, want) {
+		t.Errorf("got roles %v, want %v", identity.Roles, want)
+	}
+}
+
+func TestJWTInvalidTokens(t *testing.T) {
+	verifier, stop := newTestJWT(t)
+	defer stop()
+
+	key := signingKey(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate rsa key error: %s", err)
+	}
+	now := time.Now()
+	tests := []struct {
+		name   string
+		kid    string
+		key    *rsa.PrivateKey
+		modify func(claims map[string]interface{})
+		err    string
+	}{
+		{"unknown kid", "key-2", key, nil, "unknown key id key-2"},
+		{"bad signature", "key-1", otherKey, nil, "invalid jwt signature"},
+		{"expired", "key-1", key, func(claims map[string]interface{}) {
+			claims["exp"] = now.Add(-time.Hour).Unix()
+		}, "jwt expired"},
+		{"no exp", "key-1", key, func(claims map[string]interface{}) {
+			delete(claims, "exp")
+		}, "jwt has no exp claim"},
+		{"nbf in the future", "key-1", key, func(claims map[string]interface{}) {
+			claims["nbf"] = now.Add(time.Hour).Unix()
+		}, "jwt not valid yet"},
+		{"issuer mismatch", "key-1", key, func(claims map[string]interface{}) {
+			claims["iss"] = "https://other.example.com"
+		}, "invalid jwt issuer"},
+		{"audience mismatch", "key-1", key, func(claims map[string]interface{}) {
+			claims["aud"] = []string{"other", "another"}
+		}, "invalid jwt audience"},
+	}
+	for _, test := range tests {
+		claims := validClaims()
+		if test.modify != nil {
+			test.modify(claims)
+		}
+		token := signToken(t, test.key, test.kid, claims)
+		_, err := verifier.Verify(token)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestJWTLeeway(t *testing.T) {
+	verifier, stop := newTestJWT(t)
+	defer stop()
+
+	// A token just expired or not valid for a few more seconds is accepted
+	// because of clock skew.
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-10 * time.Second).Unix()
+	claims["nbf"] = time.Now().Add(10 * time.Second).Unix()
+	if _, err := verifier.Verify(signToken(t, signingKey(t), "key-1", claims)); err != nil {
+		t.Errorf("Verify error: %s", err)
+	}
+}
+
+func TestJWTAudienceList(t *testing.T) {
+	verifier, stop := newTestJWT(t)
+	defer stop()
+
+	claims := validClaims()
+	claims["aud"] = []string{"other", "containerops"}
+	if _, err := verifier.Verify(signToken(t, signingKey(t), "key-1", claims)); err != nil {
+		t.Errorf("Verify error: %s", err)
+	}
+}
+
+func TestJWTRolesClaim(t *testing.T) {
+	verifier, stop := newTestJWT(t)
+	defer stop()
+
+	tests := []struct {
+		roles interface{}
+		want  []Role
+	}{
+		{[]interface{}{"admin", 1, "viewer"}, []Role{RoleAdmin, RoleViewer}},
+		{"author  executor", []Role{RoleAuthor, RoleExecutor}},
+		{"", []Role{}},
+		{nil, nil},
+	}
+	for _, test := range tests {
+		claims := validClaims()
+		if test.roles == nil {
+			delete(claims, "roles")
+		} else {
+			claims["roles"] = test.roles
+		}
+		identity, err := verifier.Authenticate(bearerRequest(signToken(t, signingKey(t), "key-1", claims)))
+		if err != nil {
+			t.Errorf("roles %v: Authenticate error: %s", test.roles, err)
+			continue
+		}
+		if !reflect.DeepEqual(identity.Roles, test.want) {
+			t.Errorf("roles %v: got %v, want %v", test.roles, identity.Roles, test.want)
+		}
+	}
+}
+
+func TestJWTCustomClaims(t *testing.T) {
+	jwks := testJWKS(signingKey(t), "key-1")
+	file, err := ioutil.TempFile("", "jwks")
+	if err != nil {
+		t.Fatalf("create jwks file error: %s", err)
+	}
+	defer os.Remove(file.Name())
+	file.Write(jwks)
+	file.Close()
+
+	verifier, err := NewJWT(JWTConfig{
+		JWKS:       "file://" + file.Name(),
+		RolesClaim: "groups",
+		NameClaim:  "email",
+	})
+	if err != nil {
+		t.Fatalf("NewJWT error: %s", err)
+	}
+	claims := validClaims()
+	claims["email"] = "alice@example.com"
+	claims["groups"] = []string{"viewer"}
+	identity, err := verifier.Authenticate(bearerRequest(signToken(t, signingKey(t), "key-1", claims)))
+	if err != nil {
+		t.Fatalf("Authenticate error: %s", err)
+	}
+	if identity.Name != "alice@example.com" {
+		t.Errorf("got name %q, want alice@example.com", identity.Name)
+	}
+	if !reflect.DeepEqual(identity.Roles, []Role{RoleViewer}) {
+		t.Errorf("got roles %v, want [viewer]", identity.Roles)
+	}
+}
+
+func TestJWTNoCredentials(t *testing.T) {
+	verifier, stop := newTestJWT(t)
+	defer stop()
+
+	req, _ := http.NewRequest("GET", "/v2/components", nil)
+	if _, err := verifier.Authenticate(req); err != ErrNoCredentials {
+		t.Errorf("got error %v without a token, want ErrNoCredentials", err)
+	}
+	if _, err := verifier.Authenticate(bearerRequest("static-token")); err != ErrNoCredentials {
+		t.Errorf("got error %v for a static token, want ErrNoCredentials", err)
+	}
+}
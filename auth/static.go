@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// StaticTokens authenticates bearer tokens listed in a file.
+type StaticTokens struct {
+	identities map[string]*Identity
+}
+
+// NewStaticTokens reads a csv file with lines of token,name,roles, where
+// roles is a comma separated list and usually quoted.
+func NewStaticTokens(file string) (*StaticTokens, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.New("open token file error: " + err.Error())
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.New("read token file error: " + err.Error())
+	}
+	tokens := &StaticTokens{identities: make(map[string]*Identity)}
+	for i, record := range records {
+		if len(record) < 3 {
+			return nil, fmt.Errorf("token file line %d should be token,name,roles", i+1)
+		}
+		token := strings.TrimSpace(record[0])
+		if token == "" {
+			return nil, fmt.Errorf("token file line %d has an empty token", i+1)
+		}
+		tokens.identities[token] = &Identity{
+			Name:  strings.TrimSpace(record[1]),
+			Roles: parseRoles(strings.Split(strings.Join(record[2:], ","), ",")),
+		}
+	}
+	return tokens, nil
+}
+
+func (tokens *StaticTokens) Authenticate(req *http.Request) (*Identity, error) {
+	token := bearerToken(req)
+	if token == "" || strings.Count(token, ".") == 2 {
+		// A JWT is left to the JWT authenticator.
+		return nil, ErrNoCredentials
+	}
+	for t, identity := range tokens.identities {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return identity, nil
+		}
+	}
+	return nil, errors.New("invalid token")
+}
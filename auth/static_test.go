@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// writeTokenFile writes content into a temporary token file and returns
+// its path.
+func writeTokenFile(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "tokens")
+	if err != nil {
+		t.Fatalf("create token file error: %s", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatalf("write token file error: %s", err)
+	}
+	return file.Name()
+}
+
+func TestStaticTokens(t *testing.T) {
+	name := writeTokenFile(t, `# token,name,roles
+token-1,alice,"author,executor"
+ token-2 , bob ,viewer
+token-3,carol,admin,executor
+`)
+	defer os.Remove(name)
+
+	tokens, err := NewStaticTokens(name)
+	if err != nil {
+		t.Fatalf("NewStaticTokens error: %s", err)
+	}
+	tests := []struct {
+		token string
+		name  string
+		roles []Role
+	}{
+		{"token-1", "alice", []Role{RoleAuthor, RoleExecutor}},
+		{"token-2", "bob", []Role{RoleViewer}},
+		{"token-3", "carol", []Role{RoleAdmin, RoleExecutor}},
+	}
+	for _, test := range tests {
+		identity, err := tokens.Authenticate(bearerRequest(test.token))
+		if err != nil {
+			t.Errorf("%s: Authenticate error: %s", test.token, err)
+			continue
+		}
+		if identity.Name != test.name {
+			t.Errorf("%s: got name %q, want %q", test.token, identity.Name, test.name)
+		}
+		if !reflect.DeepEqual(identity.Roles, test.roles) {
+			t.Errorf("%s: got roles %v, want %v", test.token, identity.Roles, test.roles)
+		}
+	}
+
+	if _, err := tokens.Authenticate(bearerRequest("token-4")); err == nil || err == ErrNoCredentials {
+		t.Errorf("got error %v for an unknown token, want invalid token", err)
+	}
+	req, _ := http.NewRequest("GET", "/v2/components", nil)
+	if _, err := tokens.Authenticate(req); err != ErrNoCredentials {
+		t.Errorf("got error %v without a token, want ErrNoCredentials", err)
+	}
+	if _, err := tokens.Authenticate(bearerRequest("a.b.c")); err != ErrNoCredentials {
+		t.Errorf("got error %v for a jwt, want ErrNoCredentials", err)
+	}
+}
+
+func TestStaticTokensInvalidFile(t *testing.T) {
+	tests := []string{
+		"token-1,alice\n",
+		" ,alice,viewer\n",
+		"token-1,\"alice,viewer\n",
+	}
+	for _, content := range tests {
+		name := writeTokenFile(t, content)
+		if _, err := NewStaticTokens(name); err == nil {
+			t.Errorf("NewStaticTokens of %q succeeded, want an error", content)
+		}
+		os.Remove(name)
+	}
+	if _, err := NewStaticTokens("/nonexistent/tokens.csv"); err == nil {
+		t.Error("NewStaticTokens of a missing file succeeded, want an error")
+	}
+}
+
+func TestChain(t *testing.T) {
+	name := writeTokenFile(t, "token-1,alice,viewer\n")
+	defer os.Remove(name)
+	tokens, err := NewStaticTokens(name)
+	if err != nil {
+		t.Fatalf("NewStaticTokens error: %s", err)
+	}
+	verifier, stop := newTestJWT(t)
+	defer stop()
+	chain := Chain{tokens, verifier}
+
+	identity, err := chain.Authenticate(bearerRequest("token-1"))
+	if err != nil || identity.Name != "alice" {
+		t.Errorf("got %v, %v for a static token, want alice", identity, err)
+	}
+	identity, err = chain.Authenticate(bearerRequest(signToken(t, signingKey(t), "key-1", validClaims())))
+	if err != nil || identity.Name != "alice" || !identity.HasRole(RoleViewer) {
+		t.Errorf("got %v, %v for a jwt, want alice with viewer", identity, err)
+	}
+	req, _ := http.NewRequest("GET", "/v2/components", nil)
+	if _, err := chain.Authenticate(req); err != ErrNoCredentials {
+		t.Errorf("got error %v without a token, want ErrNoCredentials", err)
+	}
+}
@@ -70,6 +70,7 @@ func initServiceUrl() {
 }
 
 func startDeamon(cmd *cobra.Command, args []string) {
+	model.OpenDB()
 	defer model.CloseDB()
 	logFile := getLogFile(strings.TrimSpace(configure.GetString("log.file")),
 		configure.GetBool("log.append"))
@@ -82,6 +83,12 @@ func startDeamon(cmd *cobra.Command, args []string) {
 	// Set Macaron Web Middleware And Routers
 	web.SetMacaron(m)
 
+	go module.StartTimeoutScheduler()
+	go module.StartReconciler()
+	go module.StartGarbageCollector()
+	go module.StartOutbox()
+	go module.StartBroker()
+
 	switch listenMode {
 	case "http":
 		listenAddr := fmt.Sprintf("%s:%d", address, port)
@@ -17,8 +17,11 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/sosozhuang/component/model"
+	"os"
+	"strconv"
 	"strings"
 	"github.com/containerops/configure"
 	log "github.com/Sirupsen/logrus"
@@ -32,23 +35,143 @@ var databaseCmd = &cobra.Command{
 
 var migrateDatabaseCmd = &cobra.Command{
 	Use:   "migrate",
-	Short: "migrate subcommand migrate component's database.",
+	Short: "migrate subcommand migrate component's database to the latest version.",
 	Long:  ``,
 	Run:   migrateDatabase,
 }
 
+var migrateUpCmd = &cobra.Command{
+	Use:   "up [version]",
+	Short: "up subcommand apply the pending migrations, up to version if specified.",
+	Long:  ``,
+	Run:   migrateUp,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [steps]",
+	Short: "down subcommand revert the last applied migrations, one by default.",
+	Long:  ``,
+	Run:   migrateDown,
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "status subcommand list the migrations and whether they are applied.",
+	Long:  ``,
+	Run:   migrateStatus,
+}
+
+var migrateVersionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "version subcommand print the version of the database schema.",
+	Long:  ``,
+	Run:   migrateVersion,
+}
+
 func init() {
 	RootCmd.AddCommand(databaseCmd)
 
 	databaseCmd.AddCommand(migrateDatabaseCmd)
+	migrateDatabaseCmd.AddCommand(migrateUpCmd)
+	migrateDatabaseCmd.AddCommand(migrateDownCmd)
+	migrateDatabaseCmd.AddCommand(migrateStatusCmd)
+	migrateDatabaseCmd.AddCommand(migrateVersionCmd)
 }
 
-func migrateDatabase(cmd *cobra.Command, args []string) {
-	defer model.CloseDB()
+func setDatabaseLog() *os.File {
 	logFile := getLogFile(strings.TrimSpace(configure.GetString("log.file")),
 		configure.GetBool("log.append"))
 	log.SetOutput(logFile)
-	defer logFile.Close()
 	setLogLevel(strings.ToLower(configure.GetString("log.level")))
-	model.Migrate()
-}
\ No newline at end of file
+	return logFile
+}
+
+func migrateDatabase(cmd *cobra.Command, args []string) {
+	model.OpenDB()
+	defer model.CloseDB()
+	logFile := setDatabaseLog()
+	defer logFile.Close()
+	if err := model.MigrateUp(0); err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	fmt.Println("database migrated")
+}
+
+func migrateUp(cmd *cobra.Command, args []string) {
+	model.OpenDB()
+	defer model.CloseDB()
+	logFile := setDatabaseLog()
+	defer logFile.Close()
+	var target int64
+	if len(args) > 0 {
+		var err error
+		target, err = strconv.ParseInt(args[0], 10, 64)
+		if err != nil || target <= 0 {
+			fmt.Println("version should be a positive integer")
+			os.Exit(-1)
+		}
+	}
+	if err := model.MigrateUp(target); err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	printSchemaVersion()
+}
+
+func migrateDown(cmd *cobra.Command, args []string) {
+	model.OpenDB()
+	defer model.CloseDB()
+	logFile := setDatabaseLog()
+	defer logFile.Close()
+	steps := 1
+	if len(args) > 0 {
+		var err error
+		steps, err = strconv.Atoi(args[0])
+		if err != nil || steps <= 0 {
+			fmt.Println("steps should be a positive integer")
+			os.Exit(-1)
+		}
+	}
+	if err := model.MigrateDown(steps); err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	printSchemaVersion()
+}
+
+func migrateStatus(cmd *cobra.Command, args []string) {
+	model.OpenDB()
+	defer model.CloseDB()
+	logFile := setDatabaseLog()
+	defer logFile.Close()
+	statuses, err := model.MigrationStatuses()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	for _, status := range statuses {
+		if status.Applied {
+			fmt.Printf("%d %s applied at %s\n", status.Version, status.Name, status.AppliedAt.Format("2006-01-02 15:04:05"))
+		} else {
+			fmt.Printf("%d %s pending\n", status.Version, status.Name)
+		}
+	}
+}
+
+func migrateVersion(cmd *cobra.Command, args []string) {
+	model.OpenDB()
+	defer model.CloseDB()
+	logFile := setDatabaseLog()
+	defer logFile.Close()
+	printSchemaVersion()
+}
+
+func printSchemaVersion() {
+	version, err := model.SchemaVersion()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	fmt.Printf("database schema version is %d\n", version)
+}
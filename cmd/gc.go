@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/containerops/configure"
+	"github.com/sosozhuang/component/model"
+	"github.com/sosozhuang/component/module"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+var dryRun, orphans bool
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Gc subcommand delete resources left by component executions.",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		// The exit is left to here so the deferred closes in collectGarbage
+		// run first.
+		if err := collectGarbage(); err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(gcCmd)
+
+	gcCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only report the resources which would be deleted.")
+	gcCmd.Flags().BoolVar(&orphans, "orphans", false, "also delete the resources of executions not found in the database.")
+}
+
+func collectGarbage() error {
+	model.OpenDB()
+	defer model.CloseDB()
+	logFile := getLogFile(strings.TrimSpace(configure.GetString("log.file")),
+		configure.GetBool("log.append"))
+	log.SetOutput(logFile)
+	defer logFile.Close()
+	setLogLevel(strings.ToLower(configure.GetString("log.level")))
+
+	garbage, err := module.CollectGarbage(dryRun, orphans)
+	if err != nil {
+		return err
+	}
+	failed := false
+	for _, r := range garbage {
+		switch {
+		case dryRun:
+			fmt.Printf("would delete %s: %s\n", r, r.Reason)
+		case r.Deleted:
+			fmt.Printf("deleted %s: %s\n", r, r.Reason)
+		default:
+			failed = true
+			fmt.Printf("failed to delete %s: %s\n", r, r.Error)
+		}
+	}
+	fmt.Printf("%d resources found\n", len(garbage))
+	if failed {
+		return errors.New("failed to delete some resources")
+	}
+	return nil
+}
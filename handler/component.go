@@ -19,6 +19,7 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/go-macaron/sockets"
 	//"github.com/golang/groupcache/lru"
@@ -27,6 +28,7 @@ import (
 	"github.com/sosozhuang/component/module"
 	"github.com/sosozhuang/component/types"
 	"gopkg.in/macaron.v1"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
@@ -95,8 +97,24 @@ func ListComponents(ctx *macaron.Context) (httpStatus int, result []byte) {
 	if offset < 0 {
 		offset = 0
 	}
+	if token := ctx.QueryTrim("page_token"); token != "" {
+		var err error
+		offset, err = module.DecodePageToken(token)
+		if err != nil {
+			httpStatus = http.StatusBadRequest
+			resp.OK = false
+			resp.ErrorCode = ComponentError + ComponentReqBodyError
+			resp.Message = err.Error()
 
-	components, err := module.GetComponents(name, version, fuzzy, pageNum, versionNum, offset)
+			result, err = json.Marshal(resp)
+			if err != nil {
+				log.Errorln("ListComponents marshal data error: " + err.Error())
+			}
+			return
+		}
+	}
+
+	components, total, next, err := module.GetComponents(name, version, fuzzy, pageNum, versionNum, offset)
 	if err != nil {
 		httpStatus = http.StatusBadRequest
 		resp.OK = false
@@ -130,6 +148,8 @@ func ListComponents(ctx *macaron.Context) (httpStatus int, result []byte) {
 			Version: component.Version,
 		})
 	}
+	resp.Total = total
+	resp.NextPageToken = next
 
 	httpStatus = http.StatusOK
 	resp.OK = true
@@ -230,7 +250,9 @@ func CreateComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 		return
 	}
 
-	err = validateCreateImageSetting(req.ImageName, req.ImageTag, *req.ImageSetting)
+	if req.Process == nil {
+		err = validateCreateImageSetting(req.ImageName, req.ImageTag, *req.ImageSetting)
+	}
 	if err != nil {
 		httpStatus = http.StatusMethodNotAllowed
 		resp.OK = false
@@ -247,15 +269,9 @@ func CreateComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 	var component model.Component
 	component.Name = req.Name
 	component.Version = req.Version
-	component.Type = 0
-	for index, value := range model.ComponentTypes {
-		if string(value) == req.Type {
-			component.Type = index
-			break
-		}
-	}
+	component.Type = types.ComponentType(req.Type)
 
-	if req.ImageName == "" {
+	if req.ImageName == "" && req.Process == nil {
 		imageInfo, err := module.BuildImage(*req.ImageSetting)
 		if err != nil {
 			httpStatus = http.StatusBadRequest
@@ -282,7 +298,7 @@ func CreateComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 	component.ImageSetting = string(data)
 	component.Timeout = req.Timeout
 	component.UseAdvanced = req.UseAdvanced
-	kubeSetting := types.KubeSetting{Pod: req.Pod, Service: req.Service}
+	kubeSetting := types.KubeSetting{Pod: req.Pod, Service: req.Service, Job: req.Job, Process: req.Process}
 	data, err = json.Marshal(kubeSetting)
 	if err != nil {
 		log.Errorln("CreateComponent marshal KubeSetting data error: " + err.Error())
@@ -298,6 +314,12 @@ func CreateComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 	//	log.Errorln("Create component marshal Output data error: " + err.Error())
 	//}
 	component.Output = string(*req.Output)
+	if req.InputSchema != nil {
+		component.InputSchema = string(*req.InputSchema)
+	}
+	if req.OutputSchema != nil {
+		component.OutputSchema = string(*req.OutputSchema)
+	}
 	data, err = json.Marshal(req.Env)
 	if err != nil {
 		log.Errorln("CreateComponent marshal Env data error: " + err.Error())
@@ -464,7 +486,7 @@ func GetComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 		log.Errorln("GetComponent unmarshal ImageSetting data error: " + err.Error())
 	}
 	resp.Timeout = component.Timeout
-	resp.Type = string(model.ComponentTypes[component.Type])
+	resp.Type = string(component.Type)
 	resp.UseAdvanced = component.UseAdvanced
 	if err := json.Unmarshal([]byte(component.Envs), &resp.Env); err != nil {
 		log.Errorln("GetComponent unmarshal Environment data error: " + err.Error())
@@ -477,6 +499,14 @@ func GetComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 	if err := json.Unmarshal([]byte(component.Output), resp.Output); err != nil {
 		log.Errorln("GetComponent unmarshal Input data error: " + err.Error())
 	}
+	if component.InputSchema != "" {
+		inputSchema := json.RawMessage(component.InputSchema)
+		resp.InputSchema = &inputSchema
+	}
+	if component.OutputSchema != "" {
+		outputSchema := json.RawMessage(component.OutputSchema)
+		resp.OutputSchema = &outputSchema
+	}
 
 	var kubeSetting types.KubeSetting
 	if json.Unmarshal([]byte(component.KubeSetting), &kubeSetting); err != nil {
@@ -484,6 +514,8 @@ func GetComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 	}
 	resp.Pod = kubeSetting.Pod
 	resp.Service = kubeSetting.Service
+	resp.Job = kubeSetting.Job
+	resp.Process = kubeSetting.Process
 
 	result, err = json.Marshal(resp)
 	if err != nil {
@@ -615,7 +647,7 @@ func UpdateComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 		return
 	}
 
-	if req.ImageName == "" && req.ImageSetting.Name == "" {
+	if req.ImageName == "" && req.Process == nil && (req.ImageSetting == nil || req.ImageSetting.Name == "") {
 		httpStatus = http.StatusMethodNotAllowed
 		resp.OK = false
 		resp.ErrorCode = ComponentError + ComponentImageError
@@ -632,13 +664,7 @@ func UpdateComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 	component.ID = id
 	component.Name = req.Name
 	component.Version = req.Version
-	component.Type = 0
-	for index, value := range model.ComponentTypes {
-		if string(value) == req.Type {
-			component.Type = index
-			break
-		}
-	}
+	component.Type = types.ComponentType(req.Type)
 
 	old, err := module.GetComponentByID(id)
 	if err != nil {
@@ -666,7 +692,10 @@ func UpdateComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 		return
 	}
 
-	rebuild, err := validateUpdateImageSetting(req.ImageName, req.ImageTag, *req.ImageSetting, *old)
+	var rebuild bool
+	if req.Process == nil {
+		rebuild, err = validateUpdateImageSetting(req.ImageName, req.ImageTag, *req.ImageSetting, *old)
+	}
 	if err != nil {
 		httpStatus = http.StatusMethodNotAllowed
 		resp.OK = false
@@ -709,7 +738,7 @@ func UpdateComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 
 	component.Timeout = req.Timeout
 	component.UseAdvanced = req.UseAdvanced
-	kubeSetting := types.KubeSetting{Pod: req.Pod, Service: req.Service}
+	kubeSetting := types.KubeSetting{Pod: req.Pod, Service: req.Service, Job: req.Job, Process: req.Process}
 	//kubeSetting["pod"] = req.Pod
 	//kubeSetting["service"] = req.Service
 	data, err = json.Marshal(kubeSetting)
@@ -727,6 +756,12 @@ func UpdateComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 	//	log.Errorln("UpdateComponent marshal Output data error: " + err.Error())
 	//}
 	component.Output = string(*req.Output)
+	if req.InputSchema != nil {
+		component.InputSchema = string(*req.InputSchema)
+	}
+	if req.OutputSchema != nil {
+		component.OutputSchema = string(*req.OutputSchema)
+	}
 	data, err = json.Marshal(req.Env)
 	if err != nil {
 		log.Errorln("UpdateComponent marshal Env data error: " + err.Error())
@@ -825,17 +860,20 @@ func DebugComponent(ctx *macaron.Context,
 	}
 
 	var context module.ExecutionContext
-	executeChan := make(chan types.ExecuteComponentMsg)
+	// A nil subscription channel blocks until the first debug starts.
+	var subscription *module.Subscription
+	var executeChan <-chan module.Message
+	defer func() {
+		if subscription != nil {
+			subscription.Cancel()
+		}
+	}()
 	ticker := time.Tick(10 * 60 * time.Second)
 	for {
 		select {
-		case execute, ok := <-executeChan:
-			if !ok {
-				log.Errorln("DebugComponent event channel closed")
-				break
-			}
+		case execute := <-executeChan:
 			log.Debugln("DebugComponent event channel received message")
-			if len(execute.Events) == 0 {
+			if execute.Type == module.NotificationTypeStatusChanged {
 				sender <- &DebugComponentMsg{
 					DebugSeqID: context.GetExecuteSeqID(),
 					Status:     execute.Status,
@@ -863,10 +901,6 @@ func DebugComponent(ctx *macaron.Context,
 				}
 			}
 		case msg := <-receiver:
-			//if msg.DebugSeqID > 0 {
-			//	cache.Remove(msg.DebugSeqID)
-				//executeChan = make(chan types.ExecuteComponentMsg)
-			//}
 			if msg.KubeMaster == "" {
 				sender <- &DebugComponentMsg{
 					CommonResp: types.CommonResp{
@@ -877,18 +911,27 @@ func DebugComponent(ctx *macaron.Context,
 				}
 				return
 			}
-			context, err = module.StartComponent(id, "component-debug", msg.KubeMaster, *msg.Input, msg.Envs, types.NotifyUrl{}, true, msg.DebugSeqID, executeChan)
+			if subscription != nil {
+				subscription.Cancel()
+				subscription, executeChan = nil, nil
+			}
+			context, subscription, err = module.StartComponent(id, model.DebugExecutorName, msg.KubeMaster, *msg.Input, msg.Envs, types.NotifyUrl{}, true)
 			if err != nil {
-				sender <- &DebugComponentMsg{
+				debugMsg := &DebugComponentMsg{
 					CommonResp: types.CommonResp{
 						OK:        false,
 						ErrorCode: ComponentError + ComponentDebugError,
 						Message:   "debug component error: " + err.Error(),
 					},
 				}
+				if validationError, ok := err.(*module.ValidationError); ok {
+					debugMsg.ErrorCode = ComponentError + ComponentInputValidationError
+					debugMsg.ValidationErrors = validationError.Errors
+				}
+				sender <- debugMsg
 				return
 			}
-			//cache.Add(context.GetExecuteSeqID(), executeChan)
+			executeChan = subscription.C
 			sender <- &DebugComponentMsg{
 				DebugSeqID: context.GetExecuteSeqID(),
 				Input:      msg.Input,
@@ -953,7 +996,44 @@ func StartComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 		}
 		return
 	}
+	return startComponent(ctx, id)
+}
 
+// StartComponentByName executes the newest version of a component which
+// matches the version query param, a semantic version constraint such as
+// ^1.2, so callers don't have to pin component ids.
+func StartComponentByName(ctx *macaron.Context) (httpStatus int, result []byte) {
+	var resp ExecuteComponentResp
+	component, err := module.ResolveComponentVersion(ctx.Params(":name"), ctx.QueryTrim("version"))
+	if err != nil {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ComponentError + ComponentResolveVersionError
+		resp.Message = "resolve component version error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("StartComponentByName marshal data error: " + err.Error())
+		}
+		return
+	}
+	if component == nil {
+		httpStatus = http.StatusNotFound
+		resp.OK = false
+		resp.ErrorCode = ComponentError + ComponentResolveVersionError
+		resp.Message = "no component version matches " + ctx.QueryTrim("version")
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("StartComponentByName marshal data error: " + err.Error())
+		}
+		return
+	}
+	return startComponent(ctx, component.ID)
+}
+
+func startComponent(ctx *macaron.Context, id int64) (httpStatus int, result []byte) {
+	var resp ExecuteComponentResp
 	body, err := ctx.Req.Body().Bytes()
 	if err != nil {
 		httpStatus = http.StatusBadRequest
@@ -996,13 +1076,17 @@ func StartComponent(ctx *macaron.Context) (httpStatus int, result []byte) {
 	//	}
 	//	return
 	//}
-	context, err := module.StartComponent(id, req.ExecutorName, req.KubeMaster, *req.Input, req.Envs, req.NotifyUrl, false, 0, nil)
+	context, _, err := module.StartComponent(id, req.ExecutorName, req.KubeMaster, *req.Input, req.Envs, req.NotifyUrl, false)
 	if err != nil {
 		log.Errorln("StartComponent error:", err.Error())
 		httpStatus = http.StatusBadRequest
 		resp.OK = false
 		resp.ErrorCode = ComponentError + ComponentExecuteError
 		resp.Message = "start component error: " + err.Error()
+		if validationError, ok := err.(*module.ValidationError); ok {
+			resp.ErrorCode = ComponentError + ComponentInputValidationError
+			resp.ValidationErrors = validationError.Errors
+		}
 
 		result, err = json.Marshal(resp)
 		if err != nil {
@@ -1117,6 +1201,107 @@ func StopComponentExecution(ctx *macaron.Context) (httpStatus int, result []byte
 	return
 }
 
+// ListComponentExecutions lists executions filtered by the component_id,
+// executor, status, debug, created_after, created_before and since query
+// params, a page at a time. since is a duration such as 24h which sets
+// created_after relative to now, the times are RFC3339.
+func ListComponentExecutions(ctx *macaron.Context) (httpStatus int, result []byte) {
+	var resp ListExecutionsResp
+	writeError := func(message string) {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ComponentError + ComponentListExecutionsError
+		resp.Message = message
+
+		var err error
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("ListComponentExecutions marshal data error: " + err.Error())
+		}
+	}
+
+	var filter model.ExecutionFilter
+	if componentID := ctx.QueryTrim("component_id"); componentID != "" {
+		id, err := strconv.ParseInt(componentID, 10, 64)
+		if err != nil || id <= 0 {
+			writeError("component_id should be a positive integer")
+			return
+		}
+		filter.ComponentID = id
+	}
+	filter.ExecutorName = ctx.QueryTrim("executor")
+	if s := ctx.QueryTrim("status"); s != "" {
+		status, err := types.ParseExecutionStatus(s)
+		if err != nil {
+			writeError(err.Error())
+			return
+		}
+		filter.Status = &status
+	}
+	if debug := ctx.QueryTrim("debug"); debug != "" {
+		isDebug, err := strconv.ParseBool(debug)
+		if err != nil {
+			writeError("parse query param debug error: " + err.Error())
+			return
+		}
+		filter.IsDebug = &isDebug
+	}
+	if since := ctx.QueryTrim("since"); since != "" {
+		duration, err := time.ParseDuration(since)
+		if err != nil || duration <= 0 {
+			writeError("since should be a positive duration such as 24h")
+			return
+		}
+		createdAfter := time.Now().Add(-duration)
+		filter.CreatedAfter = &createdAfter
+	}
+	for param, t := range map[string]**time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+	} {
+		if value := ctx.QueryTrim(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				writeError("parse query param " + param + " error: " + err.Error())
+				return
+			}
+			*t = &parsed
+		}
+	}
+
+	executions, next, err := module.ListExecutions(filter, ctx.QueryTrim("cursor"), ctx.QueryInt("limit"))
+	if err != nil {
+		writeError(err.Error())
+		return
+	}
+
+	resp.Executions = make([]ExecutionItem, 0, len(executions))
+	for _, execution := range executions {
+		resp.Executions = append(resp.Executions, ExecutionItem{
+			ExecuteSeqID: execution.ID,
+			ComponentID:  execution.ComponentID,
+			ExecutorName: execution.Executor.Name,
+			Status:       execution.Status,
+			Type:         execution.Type,
+			ImageName:    execution.ImageName,
+			ImageTag:     execution.ImageTag,
+			IsDebug:      execution.IsDebug,
+			CreatedAt:    execution.CreatedAt,
+			UpdatedAt:    execution.UpdatedAt,
+		})
+	}
+	resp.NextCursor = next
+
+	httpStatus = http.StatusOK
+	resp.OK = true
+
+	result, err = json.Marshal(resp)
+	if err != nil {
+		log.Errorln("ListComponentExecutions marshal data error: " + err.Error())
+	}
+	return
+}
+
 func GetComponentExecution(ctx *macaron.Context) (httpStatus int, result []byte) {
 	var resp ExecuteComponentResp
 	executeSeqID := ctx.Params(":execution")
@@ -1180,6 +1365,10 @@ func GetComponentExecution(ctx *macaron.Context) (httpStatus int, result []byte)
 	kubeResp := json.RawMessage(context.GetKubeResp())
 	resp.KubeResp = &kubeResp
 	resp.Detail = context.GetDetail()
+	if context.GetOutput() != "" {
+		output := json.RawMessage(context.GetOutput())
+		resp.Output = &output
+	}
 	resp.Events = context.GetEvents()
 
 	result, err = json.Marshal(resp)
@@ -1188,3 +1377,408 @@ func GetComponentExecution(ctx *macaron.Context) (httpStatus int, result []byte)
 	}
 	return
 }
+
+// GetComponentExecutionOutput returns the json output of a finished
+// execution, which is stored when its component declares an output schema.
+func GetComponentExecutionOutput(ctx *macaron.Context) (httpStatus int, result []byte) {
+	var resp ExecutionOutputResp
+	executeSeqID := ctx.Params(":execution")
+	id, err := strconv.ParseInt(executeSeqID, 10, 64)
+	if err != nil {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ComponentError + ComponentParseIDError
+		resp.Message = "parse component id error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("GetComponentExecutionOutput marshal data error: " + err.Error())
+		}
+		return
+	}
+
+	context, err := module.GetComponentExecution(id, false)
+	if err != nil {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ComponentError + ComponentGetExecutionOutputError
+		resp.Message = "get component execution by id error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("GetComponentExecutionOutput marshal data error: " + err.Error())
+		}
+		return
+	}
+	if context == nil {
+		httpStatus = http.StatusNotFound
+		resp.OK = false
+		resp.ErrorCode = ComponentError + ComponentGetExecutionOutputError
+		resp.Message = "component execution not found"
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("GetComponentExecutionOutput marshal data error: " + err.Error())
+		}
+		return
+	}
+	if context.GetOutput() == "" {
+		httpStatus = http.StatusNotFound
+		resp.OK = false
+		resp.ErrorCode = ComponentError + ComponentGetExecutionOutputError
+		resp.Message = "component execution has no output, status is " + context.GetStatus().String()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("GetComponentExecutionOutput marshal data error: " + err.Error())
+		}
+		return
+	}
+
+	httpStatus = http.StatusOK
+	resp.OK = true
+	resp.ExecuteSeqID = context.GetExecuteSeqID()
+	output := json.RawMessage(context.GetOutput())
+	resp.Output = &output
+
+	result, err = json.Marshal(resp)
+	if err != nil {
+		log.Errorln("GetComponentExecutionOutput marshal data error: " + err.Error())
+	}
+	return
+}
+
+// GetComponentExecutionLogs writes the output of an execution as plain text,
+// it keeps writing while the execution runs if follow is true.
+func GetComponentExecutionLogs(ctx *macaron.Context) {
+	var resp types.CommonResp
+	writeError := func(httpStatus int, errorCode types.ErrCode, message string) {
+		resp.OK = false
+		resp.ErrorCode = ComponentError + errorCode
+		resp.Message = message
+		result, err := json.Marshal(resp)
+		if err != nil {
+			log.Errorln("GetComponentExecutionLogs marshal data error: " + err.Error())
+		}
+		ctx.Resp.Header().Set("Content-Type", "application/json")
+		ctx.Resp.WriteHeader(httpStatus)
+		ctx.Resp.Write(result)
+	}
+
+	executeSeqID := ctx.Params(":execution")
+	id, err := strconv.ParseInt(executeSeqID, 10, 64)
+	if err != nil {
+		writeError(http.StatusBadRequest, ComponentParseIDError, "parse component id error: "+err.Error())
+		return
+	}
+
+	var options types.LogOptions
+	options.Container = ctx.Query("container")
+	if follow := ctx.Query("follow"); follow != "" {
+		options.Follow, err = strconv.ParseBool(follow)
+		if err != nil {
+			writeError(http.StatusBadRequest, ComponentGetExecutionLogsError, "parse follow error: "+err.Error())
+			return
+		}
+	}
+	if tail := ctx.Query("tail"); tail != "" {
+		tailLines, err := strconv.ParseInt(tail, 10, 64)
+		if err != nil || tailLines < 0 {
+			writeError(http.StatusBadRequest, ComponentGetExecutionLogsError, "tail should be a non-negative integer")
+			return
+		}
+		options.TailLines = &tailLines
+	}
+	if since := ctx.Query("since"); since != "" {
+		duration, err := time.ParseDuration(since)
+		if err != nil || duration <= 0 {
+			writeError(http.StatusBadRequest, ComponentGetExecutionLogsError, "since should be a positive duration such as 10m")
+			return
+		}
+		sinceSeconds := int64(duration / time.Second)
+		if sinceSeconds == 0 {
+			sinceSeconds = 1
+		}
+		options.SinceSeconds = &sinceSeconds
+	}
+
+	stream, err := module.GetExecutionLogs(id, options)
+	if err != nil {
+		writeError(http.StatusBadRequest, ComponentGetExecutionLogsError, "get component execution logs error: "+err.Error())
+		return
+	}
+	defer stream.Close()
+
+	ctx.Resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	ctx.Resp.WriteHeader(http.StatusOK)
+	buf := make([]byte, 4096)
+	for {
+		n, err := stream.Read(buf)
+		if n > 0 {
+			if _, err := ctx.Resp.Write(buf[:n]); err != nil {
+				log.Warnln("GetComponentExecutionLogs write logs error:", err)
+				return
+			}
+			ctx.Resp.Flush()
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Errorln("GetComponentExecutionLogs read logs error:", err)
+			}
+			return
+		}
+	}
+}
+
+func notificationItems(notifications []model.Notification) []NotificationItem {
+	items := make([]NotificationItem, 0, len(notifications))
+	for _, notification := range notifications {
+		items = append(items, NotificationItem{
+			ID:            notification.ID,
+			Type:          notification.Type,
+			Url:           notification.Url,
+			Status:        notification.Status,
+			Attempts:      notification.Attempts,
+			NextAttemptAt: notification.NextAttemptAt,
+			LastError:     notification.LastError,
+			DeliveredAt:   notification.DeliveredAt,
+			CreatedAt:     notification.CreatedAt,
+		})
+	}
+	return items
+}
+
+func ListExecutionNotifications(ctx *macaron.Context) (httpStatus int, result []byte) {
+	var resp ListNotificationsResp
+	executeSeqID := ctx.Params(":execution")
+	id, err := strconv.ParseInt(executeSeqID, 10, 64)
+	if err != nil {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ComponentError + ComponentParseIDError
+		resp.Message = "parse component id error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("ListExecutionNotifications marshal data error: " + err.Error())
+		}
+		return
+	}
+
+	notifications, err := module.GetNotifications(id, ctx.QueryTrim("status"))
+	if err != nil {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ComponentError + ComponentListNotificationsError
+		resp.Message = "list notifications error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("ListExecutionNotifications marshal data error: " + err.Error())
+		}
+		return
+	}
+
+	httpStatus = http.StatusOK
+	resp.OK = true
+	resp.Notifications = notificationItems(notifications)
+	result, err = json.Marshal(resp)
+	if err != nil {
+		log.Errorln("ListExecutionNotifications marshal data error: " + err.Error())
+	}
+	return
+}
+
+// ReplayExecutionNotifications delivers the dead notifications of an
+// execution again, or only the notification in the path if there is one.
+func ReplayExecutionNotifications(ctx *macaron.Context) (httpStatus int, result []byte) {
+	var resp ListNotificationsResp
+	executeSeqID := ctx.Params(":execution")
+	id, err := strconv.ParseInt(executeSeqID, 10, 64)
+	if err != nil {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ComponentError + ComponentParseIDError
+		resp.Message = "parse component id error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("ReplayExecutionNotifications marshal data error: " + err.Error())
+		}
+		return
+	}
+	var notificationID int64
+	if param := ctx.Params(":notification"); param != "" {
+		notificationID, err = strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			httpStatus = http.StatusBadRequest
+			resp.OK = false
+			resp.ErrorCode = ComponentError + ComponentParseIDError
+			resp.Message = "parse notification id error: " + err.Error()
+
+			result, err = json.Marshal(resp)
+			if err != nil {
+				log.Errorln("ReplayExecutionNotifications marshal data error: " + err.Error())
+			}
+			return
+		}
+	}
+
+	notifications, err := module.ReplayNotifications(id, notificationID)
+	if err != nil {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ComponentError + ComponentReplayNotificationsError
+		resp.Message = "replay notifications error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("ReplayExecutionNotifications marshal data error: " + err.Error())
+		}
+		return
+	}
+
+	httpStatus = http.StatusOK
+	resp.OK = true
+	resp.Message = fmt.Sprintf("%d notifications replayed", len(notifications))
+	resp.Notifications = notificationItems(notifications)
+	result, err = json.Marshal(resp)
+	if err != nil {
+		log.Errorln("ReplayExecutionNotifications marshal data error: " + err.Error())
+	}
+	return
+}
+
+// streamHeartbeatInterval keeps idle event streams from being closed by
+// proxies.
+const streamHeartbeatInterval = 15 * time.Second
+
+// StreamComponentExecution replays the stored events of an execution as
+// server-sent events, then pushes its status changes and new events until
+// the execution terminates or the client goes away. Events already received
+// by a reconnecting client are skipped according to Last-Event-ID.
+func StreamComponentExecution(ctx *macaron.Context) {
+	var resp types.CommonResp
+	writeError := func(httpStatus int, errorCode types.ErrCode, message string) {
+		resp.OK = false
+		resp.ErrorCode = ComponentError + errorCode
+		resp.Message = message
+		result, err := json.Marshal(resp)
+		if err != nil {
+			log.Errorln("StreamComponentExecution marshal data error: " + err.Error())
+		}
+		ctx.Resp.Header().Set("Content-Type", "application/json")
+		ctx.Resp.WriteHeader(httpStatus)
+		ctx.Resp.Write(result)
+	}
+
+	executeSeqID := ctx.Params(":execution")
+	id, err := strconv.ParseInt(executeSeqID, 10, 64)
+	if err != nil {
+		writeError(http.StatusBadRequest, ComponentParseIDError, "parse component id error: "+err.Error())
+		return
+	}
+	var lastEventID int64
+	if last := ctx.Req.Header.Get("Last-Event-ID"); last != "" {
+		lastEventID, err = strconv.ParseInt(last, 10, 64)
+		if err != nil {
+			writeError(http.StatusBadRequest, ComponentStreamExecutionError, "parse Last-Event-ID error: "+err.Error())
+			return
+		}
+	}
+
+	// Subscribe before loading the execution, so nothing happening in
+	// between is lost.
+	subscription := module.SubscribeExecution(id)
+	defer subscription.Cancel()
+	context, err := module.GetComponentExecution(id, true)
+	if err != nil {
+		writeError(http.StatusBadRequest, ComponentStreamExecutionError, "get component execution error: "+err.Error())
+		return
+	}
+	if context == nil {
+		writeError(http.StatusNotFound, ComponentStreamExecutionError, "component execution not found")
+		return
+	}
+
+	ctx.Resp.Header().Set("Content-Type", "text/event-stream")
+	ctx.Resp.Header().Set("Cache-Control", "no-cache")
+	ctx.Resp.Header().Set("Connection", "keep-alive")
+	ctx.Resp.Header().Set("X-Accel-Buffering", "no")
+	ctx.Resp.WriteHeader(http.StatusOK)
+
+	writeEvent := func(event, id string, data interface{}) bool {
+		content, err := json.Marshal(data)
+		if err != nil {
+			log.Errorln("StreamComponentExecution marshal data error: " + err.Error())
+			return true
+		}
+		message := "event: " + event + "\n"
+		if id != "" {
+			message += "id: " + id + "\n"
+		}
+		message += "data: " + string(content) + "\n\n"
+		if _, err := io.WriteString(ctx.Resp, message); err != nil {
+			log.Warnln("StreamComponentExecution write event error:", err)
+			return false
+		}
+		ctx.Resp.Flush()
+		return true
+	}
+	writeStatus := func(status types.ExecutionStatus) bool {
+		return writeEvent("status", "", ExecutionStatusItem{ExecuteSeqID: id, Status: status})
+	}
+	writeEvents := func(events []types.EventMsg) bool {
+		for _, event := range events {
+			if event.ID != 0 && event.ID <= lastEventID {
+				continue
+			}
+			if !writeEvent("event", strconv.FormatInt(event.ID, 10), event) {
+				return false
+			}
+			if event.ID > lastEventID {
+				lastEventID = event.ID
+			}
+		}
+		return true
+	}
+
+	status := context.GetStatus()
+	if !writeStatus(status) || !writeEvents(context.GetEvents()) {
+		return
+	}
+	if module.IsTerminalStatus(status) {
+		return
+	}
+
+	var closed <-chan bool
+	if notifier, ok := ctx.Resp.(http.CloseNotifier); ok {
+		closed = notifier.CloseNotify()
+	}
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case msg := <-subscription.C:
+			if msg.Type != module.NotificationTypeStatusChanged && !writeEvents(msg.Events) {
+				return
+			}
+			if msg.Status != status {
+				status = msg.Status
+				if !writeStatus(status) {
+					return
+				}
+			}
+			if module.IsTerminalStatus(status) {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(ctx.Resp, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			ctx.Resp.Flush()
+		case <-closed:
+			return
+		}
+	}
+}
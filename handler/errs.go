@@ -21,6 +21,8 @@ const (
 	ComponentError  types.ErrCode = 10000
 	EventError      types.ErrCode = 20000
 	ImageError      types.ErrCode = 30000
+	ExecutorError   types.ErrCode = 40000
+	AuthError       types.ErrCode = 50000
 )
 
 const (
@@ -40,6 +42,14 @@ const (
 	ComponentStartExecutionError
 	ComponentGetExecutionError
 	ComponentStopExecutionError
+	ComponentGetExecutionLogsError
+	ComponentListNotificationsError
+	ComponentReplayNotificationsError
+	ComponentStreamExecutionError
+	ComponentInputValidationError
+	ComponentGetExecutionOutputError
+	ComponentListExecutionsError
+	ComponentResolveVersionError
 )
 
 const (
@@ -48,6 +58,7 @@ const (
 	EventUnmarshalError //errCode = 0002
 	EventIllegalDataError
 	EventGetActionError
+	EventSignatureError
 )
 
 const (
@@ -56,3 +67,20 @@ const (
 	ImageUnmarshalError
 	ImageScriptError
 )
+
+const (
+	_ = iota
+	ExecutorReqBodyError
+	ExecutorUnmarshalError
+	ExecutorCreateError
+	ExecutorListError
+	ExecutorGetError
+	ExecutorRotateError
+	ExecutorDeleteError
+)
+
+const (
+	_ = iota
+	AuthUnauthorizedError
+	AuthForbiddenError
+)
@@ -41,6 +41,20 @@ func CreateEvent(ctx *macaron.Context) (httpStatus int, result []byte) {
 		return
 	}
 
+	err = module.VerifyEvent(req.ExecuteSeqID, req.Type, ctx.Req.Header, body, ctx.RemoteAddr())
+	if err != nil {
+		httpStatus = http.StatusUnauthorized
+		resp.OK = false
+		resp.ErrorCode = EventError + EventSignatureError
+		resp.Message = "verify event error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("CreateEvent marshal data error: " + err.Error())
+		}
+		return
+	}
+
 	err = module.ReceiveEvent(req.ExecuteSeqID, req.Type, req.Content)
 	if err != nil {
 		httpStatus = http.StatusBadRequest
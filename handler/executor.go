@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/sosozhuang/component/model"
+	"github.com/sosozhuang/component/module"
+	"gopkg.in/macaron.v1"
+	"net/http"
+)
+
+func executorItem(executor *model.Executor) *ExecutorItem {
+	return &ExecutorItem{
+		ID:        executor.ID,
+		Name:      executor.Name,
+		CreatedAt: executor.CreatedAt,
+		UpdatedAt: executor.UpdatedAt,
+	}
+}
+
+func RegisterExecutor(ctx *macaron.Context) (httpStatus int, result []byte) {
+	var resp RegisterResp
+	body, err := ctx.Req.Body().Bytes()
+	if err != nil {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ExecutorError + ExecutorReqBodyError
+		resp.Message = "get requrest body error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("RegisterExecutor marshal data error: " + err.Error())
+		}
+		return
+	}
+
+	var req RegisterExecutorReq
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		log.Errorln("RegisterExecutor unmarshal data error:", err.Error())
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ExecutorError + ExecutorUnmarshalError
+		resp.Message = "unmarshal data error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("RegisterExecutor marshal data error: " + err.Error())
+		}
+		return
+	}
+
+	executor, err := module.RegisterExecutor(req.Name)
+	if err != nil {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ExecutorError + ExecutorCreateError
+		resp.Message = "register executor error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("RegisterExecutor marshal data error: " + err.Error())
+		}
+		return
+	}
+
+	httpStatus = http.StatusCreated
+	resp.OK = true
+	resp.Message = "executor registered"
+	resp.InvokerID = executor.Name
+	resp.Key = executor.Key
+
+	result, err = json.Marshal(resp)
+	if err != nil {
+		log.Errorln("RegisterExecutor marshal data error: " + err.Error())
+	}
+	return
+}
+
+func ListExecutors(ctx *macaron.Context) (httpStatus int, result []byte) {
+	var resp ListExecutorsResp
+	executors, err := module.GetExecutors()
+	if err != nil {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ExecutorError + ExecutorListError
+		resp.Message = "list executors error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("ListExecutors marshal data error: " + err.Error())
+		}
+		return
+	}
+
+	resp.Executors = make([]ExecutorItem, 0, len(executors))
+	for i := range executors {
+		resp.Executors = append(resp.Executors, *executorItem(&executors[i]))
+	}
+	httpStatus = http.StatusOK
+	resp.OK = true
+
+	result, err = json.Marshal(resp)
+	if err != nil {
+		log.Errorln("ListExecutors marshal data error: " + err.Error())
+	}
+	return
+}
+
+func GetExecutor(ctx *macaron.Context) (httpStatus int, result []byte) {
+	var resp ExecutorResp
+	executor, err := module.GetExecutor(ctx.Params(":executor"))
+	if err != nil {
+		httpStatus = http.StatusNotFound
+		resp.OK = false
+		resp.ErrorCode = ExecutorError + ExecutorGetError
+		resp.Message = "get executor error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("GetExecutor marshal data error: " + err.Error())
+		}
+		return
+	}
+
+	httpStatus = http.StatusOK
+	resp.OK = true
+	resp.ExecutorItem = executorItem(executor)
+
+	result, err = json.Marshal(resp)
+	if err != nil {
+		log.Errorln("GetExecutor marshal data error: " + err.Error())
+	}
+	return
+}
+
+func RotateExecutorKey(ctx *macaron.Context) (httpStatus int, result []byte) {
+	var resp RegisterResp
+	executor, err := module.RotateExecutorKey(ctx.Params(":executor"))
+	if err != nil {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ExecutorError + ExecutorRotateError
+		resp.Message = "rotate executor key error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("RotateExecutorKey marshal data error: " + err.Error())
+		}
+		return
+	}
+
+	httpStatus = http.StatusOK
+	resp.OK = true
+	resp.Message = "executor key rotated"
+	resp.InvokerID = executor.Name
+	resp.Key = executor.Key
+
+	result, err = json.Marshal(resp)
+	if err != nil {
+		log.Errorln("RotateExecutorKey marshal data error: " + err.Error())
+	}
+	return
+}
+
+func RevokeExecutor(ctx *macaron.Context) (httpStatus int, result []byte) {
+	var resp ExecutorResp
+	err := module.RevokeExecutor(ctx.Params(":executor"))
+	if err != nil {
+		httpStatus = http.StatusBadRequest
+		resp.OK = false
+		resp.ErrorCode = ExecutorError + ExecutorDeleteError
+		resp.Message = "revoke executor error: " + err.Error()
+
+		result, err = json.Marshal(resp)
+		if err != nil {
+			log.Errorln("RevokeExecutor marshal data error: " + err.Error())
+		}
+		return
+	}
+
+	httpStatus = http.StatusOK
+	resp.OK = true
+	resp.Message = "executor revoked"
+
+	result, err = json.Marshal(resp)
+	if err != nil {
+		log.Errorln("RevokeExecutor marshal data error: " + err.Error())
+	}
+	return
+}
@@ -2,13 +2,39 @@ package handler
 
 import (
 	"encoding/json"
+	"github.com/sosozhuang/component/module"
 	"github.com/sosozhuang/component/types"
 	"k8s.io/client-go/pkg/api/v1"
+	"time"
 )
 
+type RegisterExecutorReq struct {
+	Name string `json:"name"`
+}
+
+// RegisterResp returns the key of an executor, it is only returned when
+// the executor is registered or its key is rotated.
 type RegisterResp struct {
-	InvokerID string
-	Key       string
+	InvokerID        string `json:"invoker_id"`
+	Key              string `json:"key"`
+	types.CommonResp `json:"common"`
+}
+
+type ExecutorItem struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ExecutorResp struct {
+	*ExecutorItem    `json:"executor,omitempty"`
+	types.CommonResp `json:"common"`
+}
+
+type ListExecutorsResp struct {
+	Executors        []ExecutorItem `json:"executors"`
+	types.CommonResp `json:"common"`
 }
 
 type ComponentResp struct {
@@ -24,6 +50,8 @@ type ComponentItem struct {
 
 type ListComponentsResp struct {
 	Components       []ComponentItem `json:"components,omitempty"`
+	Total            int             `json:"total"`
+	NextPageToken    string          `json:"next_page_token,omitempty"`
 	types.CommonResp `json:"common"`
 }
 
@@ -33,6 +61,8 @@ type ComponentReq struct {
 	Version             string           `json:"version"`
 	Input               *json.RawMessage `json:"input,omitempty"`
 	Output              *json.RawMessage `json:"output,omitempty"`
+	InputSchema         *json.RawMessage `json:"input_schema,omitempty"`
+	OutputSchema        *json.RawMessage `json:"output_schema,omitempty"`
 	Env                 []types.Env      `json:"env"`
 	ImageName           string           `json:"image_name"`
 	ImageTag            string           `json:"image_tag"`
@@ -42,6 +72,8 @@ type ComponentReq struct {
 	UseAdvanced         bool        `json:"use_advanced"`
 	Pod                 *v1.Pod     `json:"pod,omitempty"`
 	Service             *v1.Service `json:"service,omitempty"`
+	Job                 *types.JobSetting     `json:"job,omitempty"`
+	Process             *types.ProcessSetting `json:"process,omitempty"`
 }
 
 type DebugComponentMsg struct {
@@ -51,6 +83,9 @@ type DebugComponentMsg struct {
 	Envs       []types.Env           `json:"envs,omitempty"`
 	Status     types.ExecutionStatus `json:"status"`
 	Event      *types.EventMsg       `json:"event,omitempty"`
+	// ValidationErrors locates the mismatches of an input against the
+	// input schema of the component.
+	ValidationErrors []module.SchemaError `json:"validation_errors,omitempty"`
 	types.CommonResp `json:"common"`
 }
 
@@ -67,6 +102,7 @@ type ExecuteComponentReq struct {
 
 type ExecuteComponentResp struct {
 	*types.ExecuteComponentMsg `json:"execute,omitempty"`
+	ValidationErrors           []module.SchemaError `json:"validation_errors,omitempty"`
 	types.CommonResp           `json:"common"`
 }
 
@@ -75,3 +111,51 @@ type CreateEventReq struct {
 	Type         types.EventType `json:"type"`
 	Content      string          `json:"content"`
 }
+
+type ExecutionItem struct {
+	ExecuteSeqID int64                 `json:"execute_seq_id"`
+	ComponentID  int64                 `json:"component_id"`
+	ExecutorName string                `json:"executor_name"`
+	Status       types.ExecutionStatus `json:"status"`
+	Type         types.ComponentType   `json:"type"`
+	ImageName    string                `json:"image_name"`
+	ImageTag     string                `json:"image_tag"`
+	IsDebug      bool                  `json:"is_debug"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+}
+
+type ListExecutionsResp struct {
+	Executions       []ExecutionItem `json:"executions"`
+	NextCursor       string          `json:"next_cursor,omitempty"`
+	types.CommonResp `json:"common"`
+}
+
+type ExecutionOutputResp struct {
+	ExecuteSeqID     int64            `json:"execute_seq_id"`
+	Output           *json.RawMessage `json:"output,omitempty"`
+	types.CommonResp `json:"common"`
+}
+
+// ExecutionStatusItem is the data of a status server-sent event.
+type ExecutionStatusItem struct {
+	ExecuteSeqID int64                 `json:"execute_seq_id"`
+	Status       types.ExecutionStatus `json:"status"`
+}
+
+type NotificationItem struct {
+	ID            int64      `json:"id"`
+	Type          string     `json:"type"`
+	Url           string     `json:"url"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type ListNotificationsResp struct {
+	Notifications    []NotificationItem `json:"notifications"`
+	types.CommonResp `json:"common"`
+}
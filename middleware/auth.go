@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/containerops/configure"
+	"github.com/sosozhuang/component/auth"
+	"github.com/sosozhuang/component/handler"
+	"github.com/sosozhuang/component/types"
+	"gopkg.in/macaron.v1"
+	"net/http"
+	"os"
+)
+
+const identityKey = "Identity"
+
+var authenticator auth.Authenticator
+
+// authEnabled is false unless auth.enabled is set, every caller has every
+// role then.
+var authEnabled bool
+
+// initAuth builds the authenticators configured in the auth section.
+func initAuth() {
+	authEnabled = configure.GetBool("auth.enabled")
+	if !authEnabled {
+		// The log may go to a file, so the warning is printed on stderr
+		// too where whoever starts the daemon sees it.
+		warning := "Authentication is disabled, every caller of the REST API has every role " +
+			"including admin. Set auth.enabled with auth.tokenfile or auth.jwks to protect it."
+		fmt.Fprintln(os.Stderr, "WARNING: "+warning)
+		log.Warnln(warning)
+		return
+	}
+	var chain auth.Chain
+	if file := configure.GetString("auth.tokenfile"); file != "" {
+		tokens, err := auth.NewStaticTokens(file)
+		if err != nil {
+			log.Fatalln("Load static tokens error:", err)
+			os.Exit(1)
+		}
+		chain = append(chain, tokens)
+	}
+	if jwks := configure.GetString("auth.jwks"); jwks != "" {
+		verifier, err := auth.NewJWT(auth.JWTConfig{
+			JWKS:       jwks,
+			Issuer:     configure.GetString("auth.issuer"),
+			Audience:   configure.GetString("auth.audience"),
+			RolesClaim: configure.GetString("auth.rolesclaim"),
+			NameClaim:  configure.GetString("auth.nameclaim"),
+		})
+		if err != nil {
+			log.Fatalln("Build jwt verifier error:", err)
+			os.Exit(1)
+		}
+		chain = append(chain, verifier)
+	}
+	if len(chain) == 0 {
+		log.Fatalln("Authentication is enabled without tokenfile or jwks")
+		os.Exit(1)
+	}
+	authenticator = chain
+}
+
+func writeAuthError(ctx *macaron.Context, httpStatus int, errorCode types.ErrCode, message string) {
+	var resp types.CommonResp
+	resp.OK = false
+	resp.ErrorCode = handler.AuthError + errorCode
+	resp.Message = message
+	result, err := json.Marshal(resp)
+	if err != nil {
+		log.Errorln("Auth marshal data error: " + err.Error())
+	}
+	ctx.Resp.Header().Set("Content-Type", "application/json")
+	ctx.Resp.WriteHeader(httpStatus)
+	ctx.Resp.Write(result)
+}
+
+// Authenticate finds the identity of the caller, a request without
+// credentials goes on anonymously and is rejected by RequireRole.
+func Authenticate() macaron.Handler {
+	return func(ctx *macaron.Context) {
+		if !authEnabled || ctx.Req.Method == "OPTIONS" {
+			return
+		}
+		identity, err := authenticator.Authenticate(ctx.Req.Request)
+		if err == auth.ErrNoCredentials {
+			return
+		}
+		if err != nil {
+			log.Warnf("Authenticate %s %s error: %s\n", ctx.Req.Method, ctx.Req.URL.Path, err)
+			writeAuthError(ctx, http.StatusUnauthorized, handler.AuthUnauthorizedError, "authenticate error: "+err.Error())
+			return
+		}
+		ctx.Data[identityKey] = identity
+	}
+}
+
+// GetIdentity returns the authenticated caller, or nil for an anonymous
+// one.
+func GetIdentity(ctx *macaron.Context) *auth.Identity {
+	identity, _ := ctx.Data[identityKey].(*auth.Identity)
+	return identity
+}
+
+// RequireRole rejects a caller without role.
+func RequireRole(role auth.Role) macaron.Handler {
+	return func(ctx *macaron.Context) {
+		if !authEnabled {
+			return
+		}
+		identity := GetIdentity(ctx)
+		if identity == nil {
+			ctx.Resp.Header().Set("WWW-Authenticate", "Bearer")
+			writeAuthError(ctx, http.StatusUnauthorized, handler.AuthUnauthorizedError, "should specify bearer token")
+			return
+		}
+		if !identity.HasRole(role) {
+			log.Warnf("%s without role %s is forbidden to %s %s\n", identity.Name, role, ctx.Req.Method, ctx.Req.URL.Path)
+			writeAuthError(ctx, http.StatusForbidden, handler.AuthForbiddenError, "role "+string(role)+" is required")
+			return
+		}
+	}
+}
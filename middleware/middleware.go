@@ -32,6 +32,7 @@ func SetMiddlewares(m *macaron.Macaron) {
 	m.Use(func(ctx *macaron.Context) {
 		ctx.Resp.Header().Set("Access-Control-Allow-Origin", "*")
 		ctx.Resp.Header().Set("Access-Control-Allow-Methods", "POST,PUT,DELETE")
+		ctx.Resp.Header().Set("Access-Control-Allow-Headers", "Authorization,Content-Type")
 	})
 
 	m.Use(func(ctx *macaron.Context) {
@@ -40,4 +41,7 @@ func SetMiddlewares(m *macaron.Macaron) {
 			ctx.Resp.Flush()
 		}
 	})
+
+	initAuth()
+	m.Use(Authenticate())
 }
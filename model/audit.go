@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+)
+
+// EventAudit records an event which was rejected because it didn't carry
+// the token of its execution.
+type EventAudit struct {
+	ID           int64  `sql:"primary_key"`
+	ExecuteSeqID int64  `sql:"not null;index:idx_event_audit_1"`
+	Type         string `sql:"null;type:varchar(30)"`
+	RemoteAddr   string `sql:"null;type:varchar(100)"`
+	Reason       string `sql:"null;type:text"`
+	CreatedAt    time.Time
+}
+
+func (a *EventAudit) TableName() string {
+	return "event_audit"
+}
+
+func (a *EventAudit) Save() error {
+	return db.Save(a).Error
+}
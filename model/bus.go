@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+)
+
+// BusMessage is an execution message published by a replica, the other
+// replicas poll for it to reach their own subscribers.
+type BusMessage struct {
+	ID           int64     `sql:"primary_key"`
+	Replica      string    `sql:"not null;type:varchar(64)"`
+	Type         string    `sql:"not null;type:varchar(30)"`
+	ExecuteSeqID int64     `sql:"not null"`
+	Payload      string    `sql:"null;size:4194304"`
+	CreatedAt    time.Time `sql:"index:idx_bus_message_1"`
+}
+
+func (m *BusMessage) TableName() string {
+	return "bus_message"
+}
+
+func (m *BusMessage) Create() error {
+	return db.Create(m).Error
+}
+
+// SelectBusMessages returns the messages created since t in publish order.
+func SelectBusMessages(t time.Time) (messages []BusMessage, err error) {
+	messages = make([]BusMessage, 0)
+	err = db.Where("created_at >= ?", t).Order("id").Find(&messages).Error
+	return
+}
+
+// DeleteBusMessages deletes the messages created before t.
+func DeleteBusMessages(t time.Time) error {
+	return db.Where("created_at < ?", t).Delete(&BusMessage{}).Error
+}
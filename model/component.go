@@ -17,7 +17,6 @@ limitations under the License.
 package model
 
 import (
-	log "github.com/Sirupsen/logrus"
 	"github.com/jinzhu/gorm"
 	"github.com/sosozhuang/component/types"
 	"time"
@@ -27,15 +26,19 @@ const (
 	ComponentTypeKubernetes types.ComponentType = "Kubernetes"
 	ComponentTypeMesos      types.ComponentType = "Mesos"
 	ComponentTypeSwarm      types.ComponentType = "Swarm"
+	ComponentTypeLocal      types.ComponentType = "Local"
 )
 
-var ComponentTypes = []types.ComponentType{ComponentTypeKubernetes, ComponentTypeMesos, ComponentTypeSwarm}
-
+// Component.InputSchema and Component.OutputSchema are json schemas, the
+// input of an execution and its component_result content are validated
+// against them. An execution keeps the output schema it was created with,
+// and stores the content of its component_result event as its output when
+// the schema is declared.
 type Component struct {
 	ID           int64  `sql:"primary_key"`
 	Name         string `sql:"not null;type:varchar(100);index:idx_component_1"`
 	Version      string `sql:"not null;type:varchar(30);index:idx_component_1"`
-	Type         int    `sql:"not null;default:0"` //0-kubernetes 1-mesos 2-swarm
+	Type         types.ComponentType `sql:"not null;type:varchar(30);default:'Kubernetes'"`
 	ImageName    string `sql:"not null;type:varchar(100)"`
 	ImageTag     string `sql:"null;type:varchar(30)"`
 	ImageSetting string `sql:"null;type:text"`
@@ -44,6 +47,8 @@ type Component struct {
 	KubeSetting  string `sql:"null;type:text"`
 	Input        string `sql:"null;type:text"`
 	Output       string `sql:"null;type:text"`
+	InputSchema  string `sql:"null;type:text"`
+	OutputSchema string `sql:"null;type:text"`
 	Envs         string `sql:"null;type:text"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
@@ -72,60 +77,49 @@ func (condition *Component) SelectComponent() (component *Component, err error)
 	return
 }
 
-func SelectComponents(name, version string, fuzzy bool, pageNum, versionNum, offset int) (components []Component, err error) {
-	var offsetCond, cond string
-	values := make([]interface{}, 0)
-	cond = " where deleted_at is null "
+// SelectComponents lists components with every version of them, versions
+// are left for the caller to sort. With an exact name total is the number
+// of its versions. Otherwise it returns pageNum names ordered by name
+// skipping the first offset names, and total is the number of names. Only
+// portable sql is used.
+func SelectComponents(name, version string, fuzzy bool, pageNum, offset int) (components []Component, total int, err error) {
+	components = make([]Component, 0)
+	query := db.Model(&Component{})
 	if name != "" {
 		if fuzzy {
-			cond = cond + " and name like ? "
-			values = append(values, name+"%")
+			query = query.Where("name like ?", name+"%")
 		} else {
-			cond = cond + " and name = ? "
-			values = append(values, name)
+			query = query.Where("name = ?", name)
 		}
 	}
 	if version != "" {
-		cond = cond + " and version = ? "
-		values = append(values, version)
-	}
-	var max int
-	if name != "" && !fuzzy {
-		offsetCond = " where version_num > ? and version_num <= ?"
-		max = offset + versionNum
-		values = append(values, offset, max)
-	} else {
-		offsetCond = " where page_num > ? and page_num <= ? and version_num <= ?"
-		max = offset + pageNum
-		values = append(values, offset, max, versionNum)
+		query = query.Where("version = ?", version)
 	}
 
-	components = make([]Component, 0)
-	tx := db.Begin()
-	defer tx.Rollback()
-	err = tx.Exec("set @page_num = 0").Error
-	if err != nil {
+	if name != "" && !fuzzy {
+		err = query.Select("id, name, version").Find(&components).Error
+		total = len(components)
 		return
 	}
-	err = tx.Exec("set @version_num = 0").Error
-	if err != nil {
+
+	if err = query.Select("count(distinct name)").Row().Scan(&total); err != nil {
 		return
 	}
-	err = tx.Exec("set @name = ''").Error
-	if err != nil {
+	var names []string
+	err = query.Order("name").Limit(pageNum).Offset(offset).
+		Pluck("distinct name", &names).Error
+	if err != nil || len(names) == 0 {
 		return
 	}
-	raw := "select id, name, version " +
-		"from (select id, name, version, " +
-		"(case when @name != name then @page_num := @page_num + 1 else @page_num end) as page_num, " +
-		"(case when @name != name then @version_num := 1 else @version_num := @version_num + 1 end) as version_num, " +
-		"@name := name " +
-		"from component " +
-		cond +
-		"order by name, version) t" +
-		offsetCond
-	log.Debugf("SelectComponents raw sql string: %s", raw)
-	err = tx.Unscoped().Raw(raw, values...).Find(&components).Error
+	err = query.Select("id, name, version").Where("name in (?)", names).
+		Order("name").Find(&components).Error
+	return
+}
+
+// SelectComponentVersions returns every version of component name.
+func SelectComponentVersions(name string) (components []Component, err error) {
+	components = make([]Component, 0)
+	err = db.Where("name = ?", name).Find(&components).Error
 	return
 }
 
@@ -141,16 +135,21 @@ type ComponentExecution struct {
 	ID          int64                 `sql:"primary_key"`
 	ExecutorID  int64                 `sql:"not null"`
 	Executor    Executor
-	ComponentID int64                 `sql:"not null"`
+	ComponentID int64                 `sql:"not null;index:idx_component_execution_2"`
 	Status      types.ExecutionStatus `sql:"not null"`
-	Type        int                   `sql:"not null;default:0"` //0-kubernetes 1-mesos 2-swarm
+	Type        types.ComponentType   `sql:"not null;type:varchar(30);default:'Kubernetes'"`
 	ImageName   string                `sql:"not null;type:varchar(100)"`
 	ImageTag    string                `sql:"null;type:varchar(30)"`
 	Timeout     int                   `sql:"null;default:0"`
+	Deadline    *time.Time            `sql:"null;index:idx_component_execution_1"`
+	Reclaimed   bool                  `sql:"not null;default:false"`
+	Secret      string                `sql:"null;type:varchar(64)"`
 	IsDebug     bool                  `sql:"not null;default:false"`
 	KubeMaster  string                `sql:"not null"`
 	KubeSetting string                `sql:"null;type:text"`
 	Input       string                `sql:"null;type:text"`
+	OutputSchema string               `sql:"null;type:text"`
+	Output      string                `sql:"null;type:text"`
 	Envs        string                `sql:"null;type:text"`
 	NotifyUrl   string                `sql:"null;type:text"`
 	KubeResp    string                `sql:"null;type:text"`
@@ -186,6 +185,136 @@ func (c *ComponentExecution) Save() error {
 	return db.Save(c).Error
 }
 
+// runningStatuses are the statuses of an execution whose deadline is
+// enforced.
+var runningStatuses = []types.ExecutionStatus{
+	types.ComponentExecutionStatusAccepted,
+	types.ComponentExecutionStatusStarted,
+}
+
+// SelectExpiredExecutionIDs returns the running executions whose deadline
+// is not after now.
+func SelectExpiredExecutionIDs(now time.Time) (ids []int64, err error) {
+	err = db.Model(&ComponentExecution{}).
+		Where("deadline <= ? and status in (?)", now, runningStatuses).
+		Pluck("id", &ids).Error
+	return
+}
+
+// ClaimExpiredExecution moves the deadline of execution id to next if it
+// is not after now. It reports false when another daemon claimed the
+// execution first, the single conditional update keeps replicas sharing one
+// database from handling the same deadline twice.
+func ClaimExpiredExecution(id int64, now, next time.Time) (bool, error) {
+	result := db.Model(&ComponentExecution{}).
+		Where("id = ? and deadline <= ?", id, now).
+		UpdateColumn("deadline", next)
+	return result.RowsAffected == 1, result.Error
+}
+
+// BackfillExecutionDeadlines sets the deadline of the running executions
+// created before the deadline column existed.
+func BackfillExecutionDeadlines() error {
+	var executions []ComponentExecution
+	err := db.Select("id, timeout, created_at").
+		Where("deadline is null and timeout > 0 and status in (?)", runningStatuses).
+		Find(&executions).Error
+	if err != nil {
+		return err
+	}
+	for _, execution := range executions {
+		deadline := execution.CreatedAt.Add(time.Duration(execution.Timeout) * time.Second)
+		err = db.Model(&ComponentExecution{}).
+			Where("id = ? and deadline is null", execution.ID).
+			UpdateColumn("deadline", deadline).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SelectRunningExecutionIDs returns the executions which are accepted or
+// started.
+func SelectRunningExecutionIDs() (ids []int64, err error) {
+	err = db.Model(&ComponentExecution{}).
+		Where("status in (?)", runningStatuses).
+		Pluck("id", &ids).Error
+	return
+}
+
+// SelectUnreclaimedExecutionIDs returns the terminated executions last
+// updated before before, whose resources may still exist.
+func SelectUnreclaimedExecutionIDs(before time.Time) (ids []int64, err error) {
+	err = db.Model(&ComponentExecution{}).
+		Where("reclaimed = ? and status not in (?) and updated_at < ?", false, runningStatuses, before).
+		Pluck("id", &ids).Error
+	return
+}
+
+// MarkExecutionReclaimed records the resources of execution id are deleted.
+func MarkExecutionReclaimed(id int64) error {
+	return db.Model(&ComponentExecution{}).Where("id = ?", id).UpdateColumn("reclaimed", true).Error
+}
+
+// SelectExecutionMasters returns the distinct component types and masters
+// of all executions.
+func SelectExecutionMasters() (masters []ComponentExecution, err error) {
+	err = db.Model(&ComponentExecution{}).
+		Select("distinct type, kube_master").
+		Find(&masters).Error
+	return
+}
+
+// ExecutionFilter selects component executions, zero fields match every
+// execution.
+type ExecutionFilter struct {
+	ComponentID   int64
+	ExecutorName  string
+	Status        *types.ExecutionStatus
+	IsDebug       *bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// SelectComponentExecutions returns at most limit executions matching filter
+// with an id less than beforeID, the newest first. A zero beforeID starts
+// from the newest execution.
+func SelectComponentExecutions(filter ExecutionFilter, beforeID int64, limit int) (executions []ComponentExecution, err error) {
+	executions = make([]ComponentExecution, 0)
+	query := db.Unscoped().Model(&ComponentExecution{})
+	if filter.ComponentID > 0 {
+		query = query.Where("component_id = ?", filter.ComponentID)
+	}
+	if filter.ExecutorName != "" {
+		var executorIDs []int64
+		err = db.Unscoped().Model(&Executor{}).Where("name = ?", filter.ExecutorName).Pluck("id", &executorIDs).Error
+		if err != nil || len(executorIDs) == 0 {
+			return
+		}
+		query = query.Where("executor_id in (?)", executorIDs)
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if filter.IsDebug != nil {
+		query = query.Where("is_debug = ?", *filter.IsDebug)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	err = query.Preload("Executor", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Order("id desc").Limit(limit).Find(&executions).Error
+	return
+}
+
 type componentExecutionTx struct {
 	tx *gorm.DB
 	*ComponentExecution
@@ -194,7 +323,13 @@ type componentExecutionTx struct {
 func SelectComponentExecutionForUpdate(id int64) (t *componentExecutionTx, err error) {
 	tx := db.Begin()
 	var result ComponentExecution
-	err = tx.Set("gorm:query_option", "FOR UPDATE").Preload("Executor").First(&result, id).Error
+	err = forUpdate(tx).Preload("Executor", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).First(&result, id).Error
+	if err != nil {
+		// A SQLite transaction holds the database lock until it ends.
+		tx.Rollback()
+	}
 	t = &componentExecutionTx{tx, &result}
 	return
 }
@@ -212,3 +347,15 @@ func (t *componentExecutionTx) Save() (err error) {
 func (t *componentExecutionTx) Rollback() {
 	t.tx.Rollback()
 }
+
+// SaveEvent saves e in the transaction of the execution, the event is
+// stored when the execution is saved.
+func (t *componentExecutionTx) SaveEvent(e *Event) error {
+	return t.tx.Save(e).Error
+}
+
+// CreateNotification creates n in the transaction of the execution, the
+// notification is stored when the execution is saved.
+func (t *componentExecutionTx) CreateNotification(n *Notification) error {
+	return t.tx.Create(n).Error
+}
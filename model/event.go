@@ -14,7 +14,7 @@ const (
 type Event struct {
 	ID           int64           `sql:"primary_key"`
 	ExecuteSeqID int64           `sql:"not null;index:idx_event_1"`
-	Type         types.EventType `sql:"not null;type:ENUM('component_start', 'component_result', 'component_stop');index:idx_event_1"`
+	Type         types.EventType `sql:"not null;type:varchar(30);index:idx_event_1"`
 	Content      string          `sql:"null;type:text"`
 	CreatedAt    time.Time
 }
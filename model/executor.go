@@ -4,10 +4,13 @@ import (
 	"time"
 )
 
+// DebugExecutorName is the executor of debug executions.
+const DebugExecutorName = "component-debug"
+
 type Executor struct {
 	ID        int64  `sql:primary_key`
 	Name      string `sql:"not null;type:varchar(30);unique_index:uix_executor_1"`
-	Key       string `sql:"not null;type:varchar(30)"`
+	Key       string `sql:"not null;type:varchar(64)"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt *time.Time
@@ -21,6 +24,47 @@ func SelectExecutorFromName(name string) (r *Executor, err error) {
 	return
 }
 
+// SelectExecutorFromID returns the executor of id even if it was deleted,
+// as the executions started before keep running.
+func SelectExecutorFromID(id int64) (r *Executor, err error) {
+	var result Executor
+	err = db.Unscoped().First(&result, id).Error
+	r = &result
+	return
+}
+
+// SelectExecutorFromNameUnscoped returns the executor named name even if
+// it was deleted.
+func SelectExecutorFromNameUnscoped(name string) (r *Executor, err error) {
+	var result Executor
+	err = db.Unscoped().Where("name = ?", name).First(&result).Error
+	r = &result
+	return
+}
+
+func SelectExecutors() (executors []Executor, err error) {
+	executors = make([]Executor, 0)
+	err = db.Order("name").Find(&executors).Error
+	return
+}
+
+// SelectExecutorsUnscoped returns every executor, including the deleted
+// ones.
+func SelectExecutorsUnscoped() (executors []Executor, err error) {
+	executors = make([]Executor, 0)
+	err = db.Unscoped().Order("name").Find(&executors).Error
+	return
+}
+
 func (e *Executor) Save() error {
 	return db.Save(e).Error
 }
+
+// SaveUnscoped saves an executor which may have been deleted.
+func (e *Executor) SaveUnscoped() error {
+	return db.Unscoped().Save(e).Error
+}
+
+func (e *Executor) Delete() error {
+	return db.Delete(e).Error
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+	"time"
+)
+
+// ExecutionLease is held by the replica watching the resources of an
+// execution, so replicas sharing one database don't watch it twice.
+type ExecutionLease struct {
+	ID           int64     `sql:"primary_key"`
+	ExecuteSeqID int64     `sql:"not null;unique_index:uix_execution_lease_1"`
+	Holder       string    `sql:"not null;type:varchar(64)"`
+	ExpiresAt    time.Time `sql:"not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (l *ExecutionLease) TableName() string {
+	return "execution_lease"
+}
+
+// ClaimExecutionLease makes holder the holder of the lease of execution
+// seqID until expiresAt. It reports false when another holder's lease is
+// not expired at now, the unique key makes the claim hold across replicas.
+func ClaimExecutionLease(seqID int64, holder string, now, expiresAt time.Time) (bool, error) {
+	err := db.Create(&ExecutionLease{ExecuteSeqID: seqID, Holder: holder, ExpiresAt: expiresAt}).Error
+	if err == nil {
+		return true, nil
+	}
+	var existing ExecutionLease
+	if e := db.Where("execute_seq_id = ?", seqID).First(&existing).Error; e == gorm.ErrRecordNotFound {
+		return false, err
+	} else if e != nil {
+		return false, e
+	}
+	// The lease of a daemon which exited without releasing it expires.
+	result := db.Model(&ExecutionLease{}).
+		Where("id = ? and (holder = ? or expires_at < ?)", existing.ID, holder, now).
+		UpdateColumns(map[string]interface{}{"holder": holder, "expires_at": expiresAt})
+	return result.RowsAffected == 1, result.Error
+}
+
+// RenewExecutionLease extends the lease of execution seqID to expiresAt, it
+// reports false when holder no longer holds it.
+func RenewExecutionLease(seqID int64, holder string, expiresAt time.Time) (bool, error) {
+	result := db.Model(&ExecutionLease{}).
+		Where("execute_seq_id = ? and holder = ?", seqID, holder).
+		UpdateColumn("expires_at", expiresAt)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReleaseExecutionLease deletes the lease of execution seqID if holder
+// holds it.
+func ReleaseExecutionLease(seqID int64, holder string) error {
+	return db.Where("execute_seq_id = ? and holder = ?", seqID, holder).Delete(&ExecutionLease{}).Error
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+	"time"
+)
+
+// ExecutionLog is the output of a component execution, archived before its
+// cluster resources are deleted.
+type ExecutionLog struct {
+	ID           int64  `sql:"primary_key"`
+	ExecuteSeqID int64  `sql:"not null;unique_index:idx_execution_log_1"`
+	Content      string `sql:"null;size:4194304"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (l *ExecutionLog) TableName() string {
+	return "execution_log"
+}
+
+func SelectExecutionLog(executeSeqID int64) (r *ExecutionLog, err error) {
+	var result ExecutionLog
+	err = db.Where("execute_seq_id = ?", executeSeqID).First(&result).Error
+	r = &result
+	return
+}
+
+// SaveExecutionLog archives content as the output of execution
+// executeSeqID, replacing any previous archive.
+func SaveExecutionLog(executeSeqID int64, content string) error {
+	var result ExecutionLog
+	err := db.Where("execute_seq_id = ?", executeSeqID).First(&result).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	result.ExecuteSeqID = executeSeqID
+	result.Content = content
+	return db.Save(&result).Error
+}
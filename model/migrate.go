@@ -0,0 +1,197 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jinzhu/gorm"
+	"sort"
+	"time"
+)
+
+// Migration changes the schema from the previous version to Version. Down
+// reverts what Up did. On MySQL schema changes can't be rolled back, so a
+// failed migration may have to be fixed by hand.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+	// DropsColumns tells Down drops columns, which SQLite supports since
+	// 3.35 only.
+	DropsColumns bool
+}
+
+// SchemaMigration records an applied migration.
+type SchemaMigration struct {
+	Version   int64  `sql:"primary_key;auto_increment:false"`
+	Name      string `sql:"not null;type:varchar(100)"`
+	AppliedAt time.Time
+}
+
+func (m *SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus tells whether a migration is applied.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+type migrationsByVersion []Migration
+
+func (m migrationsByVersion) Len() int           { return len(m) }
+func (m migrationsByVersion) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m migrationsByVersion) Less(i, j int) bool { return m[i].Version < m[j].Version }
+
+func sortedMigrations() []Migration {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Sort(migrationsByVersion(sorted))
+	return sorted
+}
+
+func appliedMigrations() (map[int64]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}).Error; err != nil {
+		return nil, errors.New("create schema_migrations table error: " + err.Error())
+	}
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, errors.New("select schema migrations error: " + err.Error())
+	}
+	applied := make(map[int64]SchemaMigration)
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+func runMigration(migration Migration, up bool) error {
+	tx := db.Begin()
+	var err error
+	if up {
+		err = migration.Up(tx)
+	} else {
+		err = migration.Down(tx)
+	}
+	if err == nil {
+		record := &SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}
+		if up {
+			err = tx.Create(record).Error
+		} else {
+			err = tx.Delete(record).Error
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// MigrateUp applies the pending migrations up to version target, all of
+// them when target is zero.
+func MigrateUp(target int64) error {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return err
+	}
+	for _, migration := range sortedMigrations() {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		log.Infof("Apply migration %d %s\n", migration.Version, migration.Name)
+		if err := runMigration(migration, true); err != nil {
+			return fmt.Errorf("apply migration %d %s error: %s", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// MigrateDown reverts the last steps applied migrations. Nothing is
+// reverted if one of them can't be reverted on the database.
+func MigrateDown(steps int) error {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return err
+	}
+	sorted := sortedMigrations()
+	reverts := make([]Migration, 0, steps)
+	for i := len(sorted) - 1; i >= 0 && len(reverts) < steps; i-- {
+		if _, ok := applied[sorted[i].Version]; ok {
+			reverts = append(reverts, sorted[i])
+		}
+	}
+	for _, migration := range reverts {
+		if migration.DropsColumns && !canDropColumns() {
+			return fmt.Errorf("migration %d %s drops columns, which SQLite supports since 3.35 only",
+				migration.Version, migration.Name)
+		}
+	}
+	for _, migration := range reverts {
+		log.Infof("Revert migration %d %s\n", migration.Version, migration.Name)
+		if err := runMigration(migration, false); err != nil {
+			return fmt.Errorf("revert migration %d %s error: %s", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// canDropColumns reports whether the database supports ALTER TABLE DROP
+// COLUMN.
+func canDropColumns() bool {
+	if Dialect() != DriverSQLite {
+		return true
+	}
+	var version string
+	if err := db.Raw("select sqlite_version()").Row().Scan(&version); err != nil {
+		log.Errorln("Select SQLite version error:", err)
+		return false
+	}
+	var major, minor int
+	if _, err := fmt.Sscanf(version, "%d.%d", &major, &minor); err != nil {
+		log.Errorf("Parse SQLite version %s error: %s\n", version, err)
+		return false
+	}
+	return major > 3 || (major == 3 && minor >= 35)
+}
+
+// MigrationStatuses returns every migration in version order.
+func MigrationStatuses() ([]MigrationStatus, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range sortedMigrations() {
+		status := MigrationStatus{Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// SchemaVersion returns the version of the last applied migration, zero
+// when none is applied.
+func SchemaVersion() (int64, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
@@ -0,0 +1,378 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/jinzhu/gorm"
+	"github.com/sosozhuang/component/types"
+	"strconv"
+	"time"
+)
+
+// migrations are applied in version order. A migration must never change
+// once released, the structs it uses are frozen copies of the models at its
+// version, so later model changes need a new migration.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_base_tables",
+		Up:      createBaseTables,
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists(&componentV1{}, &componentExecutionV1{}, &eventV1{}, &executorV1{}).Error
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add_execution_lifecycle",
+		Up:      addExecutionLifecycle,
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, &componentExecutionV2{}, []string{"idx_component_execution_1"}, "deadline", "reclaimed", "secret")
+		},
+		DropsColumns: true,
+	},
+	{
+		Version: 3,
+		Name:    "create_delivery_tables",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&executionLogV3{}, &notificationV3{}, &eventAuditV3{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists(&executionLogV3{}, &notificationV3{}, &eventAuditV3{}).Error
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add_schemas_and_output",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&componentV4{}, &componentExecutionV4{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropColumns(tx, &componentV4{}, nil, "input_schema", "output_schema"); err != nil {
+				return err
+			}
+			return dropColumns(tx, &componentExecutionV4{}, []string{"idx_component_execution_2"}, "output_schema", "output")
+		},
+		DropsColumns: true,
+	},
+	{
+		Version: 5,
+		Name:    "create_bus_message",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&busMessageV5{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists(&busMessageV5{}).Error
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create_signature_nonce",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&signatureNonceV6{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists(&signatureNonceV6{}).Error
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create_execution_lease",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&executionLeaseV7{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists(&executionLeaseV7{}).Error
+		},
+	},
+	{
+		Version: 8,
+		Name:    "seed_debug_executor",
+		Up:      seedDebugExecutor,
+		Down: func(tx *gorm.DB) error {
+			// Debug executions still refer to the executor.
+			return nil
+		},
+	},
+}
+
+func dropColumns(tx *gorm.DB, value interface{}, indexes []string, columns ...string) error {
+	for _, index := range indexes {
+		if err := tx.Model(value).RemoveIndex(index).Error; err != nil {
+			return err
+		}
+	}
+	for _, column := range columns {
+		if err := tx.Model(value).DropColumn(column).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type componentV1 struct {
+	ID           int64  `sql:"primary_key"`
+	Name         string `sql:"not null;type:varchar(100);index:idx_component_1"`
+	Version      string `sql:"not null;type:varchar(30);index:idx_component_1"`
+	Type         string `sql:"not null;type:varchar(30);default:'Kubernetes'"`
+	ImageName    string `sql:"not null;type:varchar(100)"`
+	ImageTag     string `sql:"null;type:varchar(30)"`
+	ImageSetting string `sql:"null;type:text"`
+	Timeout      int    `sql:"null;default:0"`
+	UseAdvanced  bool   `sql:"not null;default:false"`
+	KubeSetting  string `sql:"null;type:text"`
+	Input        string `sql:"null;type:text"`
+	Output       string `sql:"null;type:text"`
+	Envs         string `sql:"null;type:text"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    *time.Time
+}
+
+func (c *componentV1) TableName() string {
+	return "component"
+}
+
+type componentExecutionV1 struct {
+	ID          int64  `sql:"primary_key"`
+	ExecutorID  int64  `sql:"not null"`
+	ComponentID int64  `sql:"not null"`
+	Status      int    `sql:"not null"`
+	Type        string `sql:"not null;type:varchar(30);default:'Kubernetes'"`
+	ImageName   string `sql:"not null;type:varchar(100)"`
+	ImageTag    string `sql:"null;type:varchar(30)"`
+	Timeout     int    `sql:"null;default:0"`
+	IsDebug     bool   `sql:"not null;default:false"`
+	KubeMaster  string `sql:"not null"`
+	KubeSetting string `sql:"null;type:text"`
+	Input       string `sql:"null;type:text"`
+	Envs        string `sql:"null;type:text"`
+	NotifyUrl   string `sql:"null;type:text"`
+	KubeResp    string `sql:"null;type:text"`
+	Detail      string `sql:"null;type:text"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (c *componentExecutionV1) TableName() string {
+	return "component_execution"
+}
+
+type eventV1 struct {
+	ID           int64  `sql:"primary_key"`
+	ExecuteSeqID int64  `sql:"not null;index:idx_event_1"`
+	Type         string `sql:"not null;type:varchar(30);index:idx_event_1"`
+	Content      string `sql:"null;type:text"`
+	CreatedAt    time.Time
+}
+
+func (e *eventV1) TableName() string {
+	return "event"
+}
+
+type executorV1 struct {
+	ID        int64  `sql:"primary_key"`
+	Name      string `sql:"not null;type:varchar(30);unique_index:uix_executor_1"`
+	Key       string `sql:"not null;type:varchar(64)"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+}
+
+func (e *executorV1) TableName() string {
+	return "executor"
+}
+
+// createBaseTables creates the tables of the first release, and changes
+// the columns of a MySQL database created by it to portable types.
+func createBaseTables(tx *gorm.DB) error {
+	if tx.Dialect().GetName() == DriverMySQL {
+		if tx.HasTable(&componentV1{}) {
+			if err := migrateComponentType(tx, &componentV1{}); err != nil {
+				return err
+			}
+		}
+		if tx.HasTable(&componentExecutionV1{}) {
+			if err := migrateComponentType(tx, &componentExecutionV1{}); err != nil {
+				return err
+			}
+		}
+		if tx.HasTable(&executorV1{}) {
+			// Executor keys are hex encoded 32 bytes keys.
+			if err := tx.Model(&executorV1{}).ModifyColumn("key", "varchar(64) not null").Error; err != nil {
+				return err
+			}
+		}
+		if tx.HasTable(&eventV1{}) {
+			// The type column was an ENUM, which other databases lack.
+			if err := tx.Model(&eventV1{}).ModifyColumn("type", "varchar(30) not null").Error; err != nil {
+				return err
+			}
+		}
+	}
+	return tx.AutoMigrate(&componentV1{}, &componentExecutionV1{}, &eventV1{}, &executorV1{}).Error
+}
+
+// legacyComponentTypes maps the integer type column used by older releases
+// to component type names.
+var legacyComponentTypes = []types.ComponentType{ComponentTypeKubernetes, ComponentTypeMesos, ComponentTypeSwarm, ComponentTypeLocal}
+
+func migrateComponentType(tx *gorm.DB, value interface{}) error {
+	err := tx.Model(value).ModifyColumn("type", "varchar(30) not null default 'Kubernetes'").Error
+	if err != nil {
+		return err
+	}
+	for index, name := range legacyComponentTypes {
+		err = tx.Model(value).Where("type = ?", strconv.Itoa(index)).UpdateColumn("type", name).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type componentExecutionV2 struct {
+	ID        int64      `sql:"primary_key"`
+	Deadline  *time.Time `sql:"null;index:idx_component_execution_1"`
+	Reclaimed bool       `sql:"not null;default:false"`
+	Secret    string     `sql:"null;type:varchar(64)"`
+}
+
+func (c *componentExecutionV2) TableName() string {
+	return "component_execution"
+}
+
+// addExecutionLifecycle adds the columns of execution deadlines, resource
+// reclaiming and event tokens. The executions terminated before are marked
+// reclaimed, their resources were deleted when they were stopped.
+func addExecutionLifecycle(tx *gorm.DB) error {
+	reclaimed := tx.Dialect().HasColumn("component_execution", "reclaimed")
+	if err := tx.AutoMigrate(&componentExecutionV2{}).Error; err != nil {
+		return err
+	}
+	if reclaimed {
+		return nil
+	}
+	return tx.Model(&componentExecutionV2{}).
+		Where("status in (?)", []types.ExecutionStatus{types.ComponentExecutionStatusStoped, types.ComponentExecutionStatusFailed}).
+		UpdateColumn("reclaimed", true).Error
+}
+
+type executionLogV3 struct {
+	ID           int64  `sql:"primary_key"`
+	ExecuteSeqID int64  `sql:"not null;unique_index:idx_execution_log_1"`
+	Content      string `sql:"null;size:4194304"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (l *executionLogV3) TableName() string {
+	return "execution_log"
+}
+
+type notificationV3 struct {
+	ID            int64      `sql:"primary_key"`
+	ExecuteSeqID  int64      `sql:"not null;index:idx_notification_1"`
+	Type          string     `sql:"not null;type:varchar(30)"`
+	Url           string     `sql:"not null;type:text"`
+	Payload       string     `sql:"null;size:4194304"`
+	Status        string     `sql:"not null;type:varchar(30);index:idx_notification_2"`
+	Attempts      int        `sql:"not null;default:0"`
+	NextAttemptAt *time.Time `sql:"null;index:idx_notification_2"`
+	LastError     string     `sql:"null;type:text"`
+	DeliveredAt   *time.Time `sql:"null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (n *notificationV3) TableName() string {
+	return "notification"
+}
+
+type eventAuditV3 struct {
+	ID           int64  `sql:"primary_key"`
+	ExecuteSeqID int64  `sql:"not null;index:idx_event_audit_1"`
+	Type         string `sql:"null;type:varchar(30)"`
+	RemoteAddr   string `sql:"null;type:varchar(100)"`
+	Reason       string `sql:"null;type:text"`
+	CreatedAt    time.Time
+}
+
+func (a *eventAuditV3) TableName() string {
+	return "event_audit"
+}
+
+type componentV4 struct {
+	ID           int64  `sql:"primary_key"`
+	InputSchema  string `sql:"null;type:text"`
+	OutputSchema string `sql:"null;type:text"`
+}
+
+func (c *componentV4) TableName() string {
+	return "component"
+}
+
+type componentExecutionV4 struct {
+	ID           int64  `sql:"primary_key"`
+	ComponentID  int64  `sql:"not null;index:idx_component_execution_2"`
+	OutputSchema string `sql:"null;type:text"`
+	Output       string `sql:"null;type:text"`
+}
+
+func (c *componentExecutionV4) TableName() string {
+	return "component_execution"
+}
+
+type busMessageV5 struct {
+	ID           int64     `sql:"primary_key"`
+	Replica      string    `sql:"not null;type:varchar(64)"`
+	Type         string    `sql:"not null;type:varchar(30)"`
+	ExecuteSeqID int64     `sql:"not null"`
+	Payload      string    `sql:"null;size:4194304"`
+	CreatedAt    time.Time `sql:"index:idx_bus_message_1"`
+}
+
+func (m *busMessageV5) TableName() string {
+	return "bus_message"
+}
+
+type signatureNonceV6 struct {
+	ID        int64     `sql:"primary_key"`
+	Scope     string    `sql:"not null;type:varchar(100);unique_index:uix_signature_nonce_1"`
+	Nonce     string    `sql:"not null;type:varchar(64);unique_index:uix_signature_nonce_1"`
+	ExpiresAt time.Time `sql:"not null;index:idx_signature_nonce_1"`
+	CreatedAt time.Time
+}
+
+func (n *signatureNonceV6) TableName() string {
+	return "signature_nonce"
+}
+
+type executionLeaseV7 struct {
+	ID           int64     `sql:"primary_key"`
+	ExecuteSeqID int64     `sql:"not null;unique_index:uix_execution_lease_1"`
+	Holder       string    `sql:"not null;type:varchar(64)"`
+	ExpiresAt    time.Time `sql:"not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (l *executionLeaseV7) TableName() string {
+	return "execution_lease"
+}
+
+// seedDebugExecutor registers the executor of debug executions, which
+// debugging requires like any other execution. An executor of that name
+// kept by an earlier release, even revoked by an admin, is left as is.
+func seedDebugExecutor(tx *gorm.DB) error {
+	var count int
+	err := tx.Unscoped().Model(&executorV1{}).Where("name = ?", DebugExecutorName).Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return errors.New("generate key error: " + err.Error())
+	}
+	return tx.Create(&executorV1{Name: DebugExecutorName, Key: hex.EncodeToString(b)}).Error
+}
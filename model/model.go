@@ -5,15 +5,45 @@ import (
 	"github.com/containerops/configure"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
 	"os"
+	"strings"
 )
 
 var db *gorm.DB
 
-func init() {
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite3"
+)
+
+// driverAliases maps the accepted database.driver values to gorm dialects.
+var driverAliases = map[string]string{
+	"":           DriverMySQL,
+	"mysql":      DriverMySQL,
+	"postgres":   DriverPostgres,
+	"postgresql": DriverPostgres,
+	"sqlite":     DriverSQLite,
+	"sqlite3":    DriverSQLite,
+}
+
+// OpenDB connects to the configured database, it exits if the connection
+// fails. Commands using the database open it first, so importing the
+// package, as tests do, doesn't need a database.
+func OpenDB() {
 	var err error
-	driver := configure.GetString("database.driver")
+	name := configure.GetString("database.driver")
+	driver, ok := driverAliases[name]
+	if !ok {
+		log.Fatalf("Unsupported database driver %s, should be mysql, postgres or sqlite3\n", name)
+		os.Exit(1)
+	}
 	uri := configure.GetString("database.uri")
+	if driver == DriverSQLite {
+		uri = sqliteURI(uri)
+	}
 	if db, err = gorm.Open(driver, uri); err != nil {
 		log.Fatalf("Open database connection[%s][%s] error: %s\n", driver, uri, err.Error())
 		os.Exit(1)
@@ -22,9 +52,50 @@ func init() {
 	db.DB().Ping()
 	db.DB().SetMaxIdleConns(10)
 	db.DB().SetMaxOpenConns(100)
+	if driver == DriverSQLite {
+		// Readers don't block the single writer in WAL mode, the mode is
+		// kept in the database file.
+		if err = db.Exec("PRAGMA journal_mode=WAL").Error; err != nil {
+			log.Errorln("Set SQLite journal mode error:", err)
+		}
+	}
 	db.SingularTable(true)
 }
 
+// sqliteURI adds to uri the connection parameters SQLite needs with more
+// than one connection: a writer waits for the lock instead of failing with
+// database is locked, and a transaction takes the lock when it begins, as
+// rows selected for update are not locked otherwise.
+func sqliteURI(uri string) string {
+	params := []string{"_busy_timeout=10000", "_txlock=immediate"}
+	for _, param := range params {
+		if strings.Contains(uri, strings.SplitN(param, "=", 2)[0]+"=") {
+			continue
+		}
+		if strings.Contains(uri, "?") {
+			uri = uri + "&" + param
+		} else {
+			uri = uri + "?" + param
+		}
+	}
+	return uri
+}
+
+// Dialect returns the gorm dialect of the database, one of the Driver
+// constants.
+func Dialect() string {
+	return db.Dialect().GetName()
+}
+
+// forUpdate locks the rows selected by tx where the database supports it,
+// a SQLite transaction locks the whole database when it begins instead.
+func forUpdate(tx *gorm.DB) *gorm.DB {
+	if Dialect() == DriverSQLite {
+		return tx
+	}
+	return tx.Set("gorm:query_option", "FOR UPDATE")
+}
+
 func CloseDB() {
 	if db != nil {
 		err := db.Close()
@@ -34,8 +105,11 @@ func CloseDB() {
 	}
 }
 
+// Migrate applies every pending migration.
 func Migrate() {
-	db.AutoMigrate(&Component{}, &ComponentExecution{}, &Event{}, &Executor{})
-
+	if err := MigrateUp(0); err != nil {
+		log.Errorln("Migrate database error:", err)
+		return
+	}
 	log.Infoln("Component database structs migrated.")
 }
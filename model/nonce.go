@@ -0,0 +1,46 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+	"time"
+)
+
+// SignatureNonce is a nonce of a signed request, it is kept until a request
+// replaying it would be rejected for its stale timestamp anyway.
+type SignatureNonce struct {
+	ID        int64     `sql:"primary_key"`
+	Scope     string    `sql:"not null;type:varchar(100);unique_index:uix_signature_nonce_1"`
+	Nonce     string    `sql:"not null;type:varchar(64);unique_index:uix_signature_nonce_1"`
+	ExpiresAt time.Time `sql:"not null;index:idx_signature_nonce_1"`
+	CreatedAt time.Time
+}
+
+func (n *SignatureNonce) TableName() string {
+	return "signature_nonce"
+}
+
+// UseNonce records nonce in scope until expiresAt, it reports false when the
+// nonce is already recorded and not expired at now. The unique key makes
+// the check hold across replicas.
+func UseNonce(scope, nonce string, now, expiresAt time.Time) (bool, error) {
+	err := db.Create(&SignatureNonce{Scope: scope, Nonce: nonce, ExpiresAt: expiresAt}).Error
+	if err == nil {
+		return true, nil
+	}
+	var existing SignatureNonce
+	if e := db.Where("scope = ? and nonce = ?", scope, nonce).First(&existing).Error; e == gorm.ErrRecordNotFound {
+		return false, err
+	} else if e != nil {
+		return false, e
+	}
+	// An expired nonce is not pruned yet, it may be used again.
+	result := db.Model(&SignatureNonce{}).
+		Where("id = ? and expires_at < ?", existing.ID, now).
+		UpdateColumn("expires_at", expiresAt)
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteExpiredNonces deletes the nonces expired before t.
+func DeleteExpiredNonces(t time.Time) error {
+	return db.Where("expires_at < ?", t).Delete(&SignatureNonce{}).Error
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	NotificationStatusPending   = "pending"
+	NotificationStatusDelivered = "delivered"
+	NotificationStatusDead      = "dead"
+)
+
+// Notification is a webhook request in the outbox, it is retried until it
+// is delivered or has been attempted too many times.
+type Notification struct {
+	ID            int64      `sql:"primary_key"`
+	ExecuteSeqID  int64      `sql:"not null;index:idx_notification_1"`
+	Type          string     `sql:"not null;type:varchar(30)"`
+	Url           string     `sql:"not null;type:text"`
+	Payload       string     `sql:"null;size:4194304"`
+	Status        string     `sql:"not null;type:varchar(30);index:idx_notification_2"`
+	Attempts      int        `sql:"not null;default:0"`
+	NextAttemptAt *time.Time `sql:"null;index:idx_notification_2"`
+	LastError     string     `sql:"null;type:text"`
+	DeliveredAt   *time.Time `sql:"null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (n *Notification) TableName() string {
+	return "notification"
+}
+
+func (n *Notification) Create() error {
+	return db.Create(n).Error
+}
+
+func (n *Notification) Save() error {
+	return db.Save(n).Error
+}
+
+func SelectNotification(id int64) (r *Notification, err error) {
+	var result Notification
+	err = db.First(&result, id).Error
+	r = &result
+	return
+}
+
+// SelectNotifications returns the notifications of an execution, all of
+// them if status is empty.
+func SelectNotifications(executeSeqID int64, status string) (notifications []Notification, err error) {
+	query := db.Where("execute_seq_id = ?", executeSeqID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	notifications = make([]Notification, 0)
+	err = query.Order("id").Find(&notifications).Error
+	return
+}
+
+// SelectDueNotificationIDs returns the pending notifications whose next
+// attempt is not after now.
+func SelectDueNotificationIDs(now time.Time, limit int) (ids []int64, err error) {
+	err = db.Model(&Notification{}).
+		Where("status = ? and next_attempt_at <= ?", NotificationStatusPending, now).
+		Order("next_attempt_at").Limit(limit).
+		Pluck("id", &ids).Error
+	return
+}
+
+// ClaimNotification moves the next attempt of a pending notification from
+// due, the value read from the database, to next. It reports false when
+// another daemon changed the notification first.
+func ClaimNotification(id int64, due, next time.Time) (bool, error) {
+	result := db.Model(&Notification{}).
+		Where("id = ? and status = ? and next_attempt_at = ?", id, NotificationStatusPending, due).
+		UpdateColumn("next_attempt_at", next)
+	return result.RowsAffected == 1, result.Error
+}
+
+// UpdateNotification updates columns of notification id if its status is
+// still status, it reports false when another daemon changed the status
+// first.
+func UpdateNotification(id int64, status string, columns map[string]interface{}) (bool, error) {
+	result := db.Model(&Notification{}).
+		Where("id = ? and status = ?", id, status).
+		Updates(columns)
+	return result.RowsAffected == 1, result.Error
+}
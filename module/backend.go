@@ -0,0 +1,99 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sosozhuang/component/types"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// Capability is a feature which a backend may support.
+type Capability uint
+
+const (
+	// CapabilityService means the backend can expose a component through
+	// the service defined in its kubernetes setting.
+	CapabilityService Capability = 1 << iota
+	// CapabilityLogs means the backend can read the output of an execution.
+	CapabilityLogs
+	// CapabilityStop means an execution can be stopped before it finishes.
+	CapabilityStop
+)
+
+// Backend runs the executions of one component type.
+type Backend interface {
+	// Capabilities returns the features supported by the backend.
+	Capabilities() Capability
+	// Prepare validates master and gets the cluster ready for executions
+	// of executorName, it returns the master which executions should use.
+	Prepare(executorName, master string) (string, error)
+	// NewComponent returns the component which runs execution seqID on master.
+	NewComponent(seqID int64, master string) (Component, error)
+}
+
+var backends = struct {
+	sync.RWMutex
+	m map[types.ComponentType]Backend
+}{m: make(map[types.ComponentType]Backend)}
+
+// RegisterBackend makes a backend available by the component type name,
+// it panics if name is registered twice or backend is nil.
+func RegisterBackend(name types.ComponentType, backend Backend) {
+	backends.Lock()
+	defer backends.Unlock()
+	if backend == nil {
+		panic("module: register backend is nil")
+	}
+	if _, ok := backends.m[name]; ok {
+		panic("module: register backend twice for " + string(name))
+	}
+	backends.m[name] = backend
+}
+
+// GetBackend returns the backend registered by the component type name.
+func GetBackend(name types.ComponentType) (Backend, bool) {
+	backends.RLock()
+	defer backends.RUnlock()
+	backend, ok := backends.m[name]
+	return backend, ok
+}
+
+// BackendNames returns the sorted names of the registered backends.
+func BackendNames() []types.ComponentType {
+	backends.RLock()
+	defer backends.RUnlock()
+	names := make([]string, 0, len(backends.m))
+	for name := range backends.m {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	result := make([]types.ComponentType, len(names))
+	for i, name := range names {
+		result[i] = types.ComponentType(name)
+	}
+	return result
+}
+
+func getBackend(name types.ComponentType) (Backend, error) {
+	backend, ok := GetBackend(name)
+	if !ok {
+		return nil, fmt.Errorf("invalid component type: %s", name)
+	}
+	return backend, nil
+}
+
+func validateMaster(master string) error {
+	if master == "" {
+		return errors.New("should specify kubernetes master when execute a component")
+	}
+	u, err := url.Parse(master)
+	if err != nil {
+		return errors.New("parse kubeMaster error: " + err.Error())
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("invalid kubeMaster url scheme: " + u.Scheme)
+	}
+	return nil
+}
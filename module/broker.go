@@ -0,0 +1,148 @@
+package module
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/containerops/configure"
+	"github.com/sosozhuang/component/model"
+	"os"
+	"time"
+)
+
+const (
+	defaultBrokerInterval  = 500 * time.Millisecond
+	defaultBrokerLookback  = 10 * time.Second
+	defaultBrokerRetention = time.Hour
+	brokerPruneInterval    = time.Minute
+)
+
+// Broker fans the messages published on the bus of a replica out to the
+// buses of the other replicas, so an event received by any replica reaches
+// the subscribers held by every replica.
+type Broker interface {
+	// Publish sends msg, which was already published on the local bus, to
+	// the other replicas.
+	Publish(msg Message) error
+	// Start passes the messages published by any replica to deliver.
+	Start(deliver func(Message))
+}
+
+// replicaID identifies the messages published by this daemon.
+var replicaID string
+
+var broker Broker
+
+func init() {
+	hostname, _ := os.Hostname()
+	nonce, err := newNonce()
+	if err != nil {
+		log.Warnln("Generate replica id error:", err)
+	}
+	replicaID = fmt.Sprintf("%s-%d-%.8s", hostname, os.Getpid(), nonce)
+
+	driver := configure.GetString("broker.driver")
+	switch driver {
+	case "", "memory":
+		broker = new(memoryBroker)
+	case "database":
+		broker = &databaseBroker{
+			interval:  configDuration("broker.interval", defaultBrokerInterval),
+			lookback:  configDuration("broker.lookback", defaultBrokerLookback),
+			retention: configDuration("broker.retention", defaultBrokerRetention),
+		}
+	default:
+		log.Warnf("Invalid broker driver %q, use memory\n", driver)
+		broker = new(memoryBroker)
+	}
+}
+
+// StartBroker publishes the messages of the other replicas on the local
+// bus, it returns at once for the memory broker.
+func StartBroker() {
+	broker.Start(func(msg Message) {
+		if msg.Replica == replicaID {
+			return
+		}
+		bus.Publish(msg)
+	})
+}
+
+// memoryBroker serves a single replica, whose bus already has every
+// message.
+type memoryBroker struct{}
+
+func (b *memoryBroker) Publish(msg Message) error {
+	return nil
+}
+
+func (b *memoryBroker) Start(deliver func(Message)) {}
+
+// databaseBroker shares messages through the bus_message table. Replicas
+// poll for the messages created since their last poll minus lookback, which
+// covers transactions committed late and clock skew between replicas, and
+// skip the messages they have seen.
+type databaseBroker struct {
+	interval  time.Duration
+	lookback  time.Duration
+	retention time.Duration
+}
+
+func (b *databaseBroker) Publish(msg Message) error {
+	payload, err := json.Marshal(msg.ExecuteComponentMsg)
+	if err != nil {
+		return errors.New("marshal message error: " + err.Error())
+	}
+	busMessage := &model.BusMessage{
+		Replica:      msg.Replica,
+		Type:         msg.Type,
+		ExecuteSeqID: msg.ExecuteSeqID,
+		Payload:      string(payload),
+	}
+	if err := busMessage.Create(); err != nil {
+		return errors.New("create bus message error: " + err.Error())
+	}
+	return nil
+}
+
+func (b *databaseBroker) Start(deliver func(Message)) {
+	seen := make(map[int64]time.Time)
+	since := time.Now()
+	var pruned time.Time
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		now := time.Now()
+		messages, err := model.SelectBusMessages(since.Add(-b.lookback))
+		if err != nil {
+			log.Errorln("Broker select bus messages error:", err)
+			continue
+		}
+		since = now
+		for _, message := range messages {
+			if _, ok := seen[message.ID]; ok {
+				continue
+			}
+			seen[message.ID] = message.CreatedAt
+			msg := Message{Type: message.Type, Replica: message.Replica}
+			if err := json.Unmarshal([]byte(message.Payload), &msg.ExecuteComponentMsg); err != nil {
+				log.Errorf("Broker unmarshal bus message %d error: %s\n", message.ID, err)
+				continue
+			}
+			deliver(msg)
+		}
+		for id, createdAt := range seen {
+			if now.Sub(createdAt) > 2*b.lookback+b.interval {
+				delete(seen, id)
+			}
+		}
+
+		if now.Sub(pruned) >= brokerPruneInterval {
+			pruned = now
+			if err := model.DeleteBusMessages(now.Add(-b.retention)); err != nil {
+				log.Errorln("Broker delete bus messages error:", err)
+			}
+		}
+	}
+}
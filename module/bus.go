@@ -0,0 +1,198 @@
+package module
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/sosozhuang/component/types"
+	"sync"
+)
+
+// Policy decides what a subscription does with a message when its buffer
+// is full.
+type Policy int
+
+const (
+	// PolicyDropNewest discards the message being published.
+	PolicyDropNewest Policy = iota
+	// PolicyDropOldest discards the oldest buffered message to make room,
+	// so the latest status is never lost.
+	PolicyDropOldest
+)
+
+func (policy Policy) String() string {
+	switch policy {
+	case PolicyDropNewest:
+		return "drop-newest"
+	case PolicyDropOldest:
+		return "drop-oldest"
+	default:
+		return "undefined"
+	}
+}
+
+// Message is published whenever an execution changes status or receives an
+// event. Type is NotificationTypeStatusChanged or the event type, Replica is
+// the daemon which published it.
+type Message struct {
+	Type    string
+	Replica string
+	types.ExecuteComponentMsg
+}
+
+// Subscription receives the messages of one execution. C is never closed,
+// Cancel must be called once the subscriber stops reading.
+type Subscription struct {
+	C <-chan Message
+
+	c      chan Message
+	seqID  int64
+	policy Policy
+	bus    *Bus
+	once   sync.Once
+}
+
+func (s *Subscription) Cancel() {
+	s.once.Do(func() {
+		s.bus.remove(s)
+	})
+}
+
+func (s *Subscription) send(msg Message) {
+	switch s.policy {
+	case PolicyDropOldest:
+		for {
+			select {
+			case s.c <- msg:
+				return
+			default:
+			}
+			select {
+			case <-s.c:
+				log.Warnf("Execution %d subscriber is full, oldest message dropped\n", msg.ExecuteSeqID)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.c <- msg:
+		default:
+			log.Warnf("Execution %d subscriber is full, message dropped\n", msg.ExecuteSeqID)
+		}
+	}
+}
+
+// Bus delivers execution messages to the subscriptions of the daemon, the
+// debug websockets and the execution streams. Webhooks don't subscribe, a
+// message is lost if the daemon exits before delivering it, so they are
+// saved in the outbox along with the execution instead.
+type Bus struct {
+	sync.RWMutex
+	subs map[int64]map[*Subscription]bool
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[int64]map[*Subscription]bool),
+	}
+}
+
+var bus = NewBus()
+
+// Subscribe returns a subscription to the messages of execution seqID, which
+// buffers up to buffer messages.
+func (bus *Bus) Subscribe(seqID int64, buffer int, policy Policy) *Subscription {
+	s := bus.newSubscription(seqID, buffer, policy)
+	bus.Lock()
+	if bus.subs[seqID] == nil {
+		bus.subs[seqID] = make(map[*Subscription]bool)
+	}
+	bus.subs[seqID][s] = true
+	bus.Unlock()
+	return s
+}
+
+func (bus *Bus) newSubscription(seqID int64, buffer int, policy Policy) *Subscription {
+	if buffer < 1 {
+		buffer = 1
+	}
+	c := make(chan Message, buffer)
+	return &Subscription{
+		C:      c,
+		c:      c,
+		seqID:  seqID,
+		policy: policy,
+		bus:    bus,
+	}
+}
+
+func (bus *Bus) remove(s *Subscription) {
+	bus.Lock()
+	defer bus.Unlock()
+	delete(bus.subs[s.seqID], s)
+	if len(bus.subs[s.seqID]) == 0 {
+		delete(bus.subs, s.seqID)
+	}
+}
+
+// Publish sends msg to the subscriptions of its execution, the bus is not
+// locked while sending.
+func (bus *Bus) Publish(msg Message) {
+	bus.RLock()
+	subs := make([]*Subscription, 0, len(bus.subs[msg.ExecuteSeqID]))
+	for s := range bus.subs[msg.ExecuteSeqID] {
+		subs = append(subs, s)
+	}
+	bus.RUnlock()
+	for _, s := range subs {
+		s.send(msg)
+	}
+}
+
+// executionSubscriptionBuffer is the buffer of the subscriptions made by
+// api clients, which keep the latest messages when they are slow.
+const executionSubscriptionBuffer = 64
+
+// SubscribeExecution returns a subscription to the status changes and
+// events of execution seqID from now on.
+func SubscribeExecution(seqID int64) *Subscription {
+	return bus.Subscribe(seqID, executionSubscriptionBuffer, PolicyDropOldest)
+}
+
+// executionMessage returns the current state of an execution along with
+// events, which are all the events of the execution on a status change and
+// the received event otherwise.
+func executionMessage(context ExecutionContext, messageType string, events []types.EventMsg) Message {
+	var msg types.ExecuteComponentMsg
+	msg.ExecuteSeqID = context.GetExecuteSeqID()
+	msg.ComponentID = context.GetComponentID()
+	msg.Status = context.GetStatus()
+	msg.Type = context.GetType()
+	msg.ImageName = context.GetImageName()
+	msg.ImageTag = context.GetImageTag()
+	msg.Timeout = context.GetTimeout()
+	msg.KubeMaster = context.GetKubeMaster()
+	kubeSetting := json.RawMessage(context.GetKubeSetting())
+	msg.KubeSetting = &kubeSetting
+	input := json.RawMessage(context.GetInput())
+	msg.Input = &input
+	msg.Envs = context.GetEnvs()
+	msg.NotifyUrl = context.GetNotifyUrl()
+	kubeResp := json.RawMessage(context.GetKubeResp())
+	msg.KubeResp = &kubeResp
+	msg.Detail = context.GetDetail()
+	if context.GetOutput() != "" {
+		output := json.RawMessage(context.GetOutput())
+		msg.Output = &output
+	}
+	msg.Events = events
+	return Message{Type: messageType, Replica: replicaID, ExecuteComponentMsg: msg}
+}
+
+// publishMessage publishes message to the subscribers of every replica, it
+// only serves live subscribers, webhooks are saved along with the execution.
+func publishMessage(message Message) {
+	bus.Publish(message)
+	if err := broker.Publish(message); err != nil {
+		log.Errorf("Broker publish message of execution %d error: %s\n", message.ExecuteSeqID, err)
+	}
+}
@@ -0,0 +1,88 @@
+package module
+
+import (
+	"github.com/sosozhuang/component/types"
+	"reflect"
+	"testing"
+)
+
+func busMessage(seqID int64, messageType string) Message {
+	var msg types.ExecuteComponentMsg
+	msg.ExecuteSeqID = seqID
+	return Message{Type: messageType, ExecuteComponentMsg: msg}
+}
+
+// received returns the types of the messages buffered by s.
+func received(s *Subscription) []string {
+	result := make([]string, 0)
+	for {
+		select {
+		case msg := <-s.C:
+			result = append(result, msg.Type)
+		default:
+			return result
+		}
+	}
+}
+
+func TestBusPolicies(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		want   []string
+	}{
+		{PolicyDropNewest, []string{"m1", "m2"}},
+		{PolicyDropOldest, []string{"m3", "m4"}},
+	}
+	for _, test := range tests {
+		bus := NewBus()
+		s := bus.Subscribe(1, 2, test.policy)
+		for _, messageType := range []string{"m1", "m2", "m3", "m4"} {
+			bus.Publish(busMessage(1, messageType))
+		}
+		if got := received(s); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.policy, got, test.want)
+		}
+		s.Cancel()
+	}
+}
+
+func TestBusExecutions(t *testing.T) {
+	bus := NewBus()
+	s1 := bus.Subscribe(1, 4, PolicyDropOldest)
+	defer s1.Cancel()
+	s2 := bus.Subscribe(2, 4, PolicyDropOldest)
+	defer s2.Cancel()
+
+	bus.Publish(busMessage(1, "m1"))
+	bus.Publish(busMessage(2, "m2"))
+	bus.Publish(busMessage(3, "m3"))
+	if got := received(s1); !reflect.DeepEqual(got, []string{"m1"}) {
+		t.Errorf("execution 1 got %v, want [m1]", got)
+	}
+	if got := received(s2); !reflect.DeepEqual(got, []string{"m2"}) {
+		t.Errorf("execution 2 got %v, want [m2]", got)
+	}
+}
+
+func TestBusCancel(t *testing.T) {
+	bus := NewBus()
+	s1 := bus.Subscribe(1, 4, PolicyDropOldest)
+	s2 := bus.Subscribe(1, 4, PolicyDropOldest)
+	defer s2.Cancel()
+
+	s1.Cancel()
+	// Cancelling twice is harmless.
+	s1.Cancel()
+	bus.Publish(busMessage(1, "m1"))
+	if got := received(s1); len(got) != 0 {
+		t.Errorf("cancelled subscription got %v, want none", got)
+	}
+	if got := received(s2); !reflect.DeepEqual(got, []string{"m1"}) {
+		t.Errorf("subscription got %v, want [m1]", got)
+	}
+
+	s2.Cancel()
+	if len(bus.subs) != 0 {
+		t.Errorf("got %d executions subscribed after cancelling, want none", len(bus.subs))
+	}
+}
@@ -1,41 +1,25 @@
 package module
 
 import (
-	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	log "github.com/Sirupsen/logrus"
 	"github.com/jinzhu/gorm"
 	"github.com/sosozhuang/component/model"
 	"github.com/sosozhuang/component/types"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/pkg/api/v1"
-	"k8s.io/client-go/tools/clientcmd"
-	"net/http"
 	"net/url"
+	"sort"
 	"time"
 	"strconv"
-	"github.com/golang/groupcache/lru"
+	"strings"
 )
 
-var cache *lru.Cache
 var ServiceUrl string
 
-func init() {
-	//TODO: may want to configure max entries number
-	cache = lru.New(50)
-	cache.OnEvicted = func(key lru.Key, value interface{}) {
-		log.Warnf("Component message channel key %v evicted", key)
-		channel, ok := value.(chan types.ExecuteComponentMsg)
-		if !ok {
-			log.Warnf("Can't convert cache value %T to message channel", value)
-			return
-		}
-		close(channel)
-	}
-}
-
 type Component interface {
 	Start()
 	Stop()
@@ -65,6 +49,8 @@ type ExecutionContext interface {
 	GetKubeResp() string
 	GetDetail() string
 	GetEvents() []types.EventMsg
+	GetSecret() string
+	GetOutput() string
 }
 
 func (context *componentExecutionContext) GetExecuteSeqID() int64 {
@@ -88,11 +74,7 @@ func (context *componentExecutionContext) GetStatus() types.ExecutionStatus {
 }
 
 func (context *componentExecutionContext) GetType() types.ComponentType {
-	if context.Type >= len(model.ComponentTypes) {
-		log.Errorf("Context has invalid component type: %d\n", context.Type)
-		return "undefined"
-	}
-	return model.ComponentTypes[context.Type]
+	return context.Type
 }
 
 func (context *componentExecutionContext) GetImageName() string {
@@ -155,10 +137,19 @@ func (context *componentExecutionContext) GetDetail() string {
 	return context.Detail
 }
 
+func (context *componentExecutionContext) GetSecret() string {
+	return context.Secret
+}
+
+func (context *componentExecutionContext) GetOutput() string {
+	return context.Output
+}
+
 func (context *componentExecutionContext) GetEvents() []types.EventMsg {
 	events := make([]types.EventMsg, 0)
 	for _, event := range context.Events {
 		events = append(events, types.EventMsg{
+			ID:           event.ID,
 			ExecuteSeqID: context.GetExecuteSeqID(),
 			Type:     event.Type,
 			Content:  event.Content,
@@ -168,23 +159,199 @@ func (context *componentExecutionContext) GetEvents() []types.EventMsg {
 	return events
 }
 
-type kubeComponent struct {
-	SeqID int64
-	c     *kubernetes.Clientset
-	//mu    sync.Mutex
+// eventTokenEnv holds the token of an execution, which is sent along with
+// or signs the events sent to CO_EVENT_URL.
+const eventTokenEnv = "CO_EVENT_TOKEN"
+
+// executionEnvs returns the component envs followed by the CO_* variables
+// every component container expects.
+func executionEnvs(context ExecutionContext) []types.Env {
+	envs := context.GetEnvs()
+	envs = append(envs, types.Env{
+		Key: "CO_EXECUTE_SEQ_ID",
+		Value: strconv.FormatInt(context.GetExecuteSeqID(), 10),
+	}, types.Env{
+		Key: "CO_EXECUTE_TIMEOUT",
+		Value: strconv.Itoa(context.GetTimeout()),
+	}, types.Env{
+		Key: "CO_INPUT",
+		Value: context.GetInput(),
+	}, types.Env{
+		Key: "CO_EVENT_URL",
+		Value: ServiceUrl + "/v2/events",
+	}, types.Env{
+		Key: eventTokenEnv,
+		Value: context.GetSecret(),
+	})
+	return envs
+}
+
+// resource is implemented by every component type, it creates and deletes
+// the cluster objects which run a component execution.
+type resource interface {
+	fmt.Stringer
+	kind() string
+	create(context ExecutionContext) (interface{}, error)
+	delete(context ExecutionContext) error
+}
+
+// inspector is implemented by a resource which can tell whether its cluster
+// objects still exist.
+type inspector interface {
+	exists(context ExecutionContext) (bool, error)
+}
+
+// errExistenceUnknown is returned by an inspector which can't tell whether
+// the objects of an execution exist, the reconciler leaves the execution
+// alone then.
+var errExistenceUnknown = errors.New("resource existence is unknown")
+
+// watcher is implemented by a resource which follows its cluster objects
+// after they are created.
+type watcher interface {
+	watch(context ExecutionContext)
+}
+
+// logger is implemented by a resource which can read the output of its
+// cluster objects.
+type logger interface {
+	logs(context ExecutionContext, options types.LogOptions) (io.ReadCloser, error)
+}
+
+// maxArchivedLogSize is the size of the output kept for an execution, the
+// start of a longer output is dropped.
+const maxArchivedLogSize = 4 << 20
+
+// archiveLogs saves the output of an execution into the database, it should
+// be called before the resources of the execution are deleted.
+func archiveLogs(r resource, context ExecutionContext) {
+	l, ok := r.(logger)
+	if !ok {
+		return
+	}
+	stream, err := l.logs(context, types.LogOptions{})
+	if err != nil {
+		log.Warnf("Archive logs of %s error: %s\n", r, err)
+		return
+	}
+	defer stream.Close()
+	data, err := readTail(stream, maxArchivedLogSize)
+	if err != nil {
+		log.Warnf("Archive logs of %s read error: %s\n", r, err)
+	}
+	// The objects may be deleted already, an earlier archive is kept then.
+	if len(data) == 0 {
+		return
+	}
+	err = model.SaveExecutionLog(context.GetExecuteSeqID(), string(data))
+	if err != nil {
+		log.Errorf("Archive logs of %s save error: %s\n", r, err)
+	}
+}
+
+// readTail reads r to the end and returns at most the last n bytes, it
+// holds no more than twice n bytes whatever the length of r.
+func readTail(r io.Reader, n int) ([]byte, error) {
+	data := make([]byte, 0, n)
+	chunk := make([]byte, 32<<10)
+	for {
+		count, err := r.Read(chunk)
+		data = append(data, chunk[:count]...)
+		if len(data) > 2*n {
+			data = append(data[:0], data[len(data)-n:]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return tail(data, n), err
+		}
+	}
+	return tail(data, n), nil
+}
+
+func tail(data []byte, n int) []byte {
+	if len(data) > n {
+		return data[len(data)-n:]
+	}
+	return data
+}
+
+// archiveExecution archives the logs of an execution through the backend
+// of its type, it is called on every transition to a terminal status.
+func archiveExecution(context ExecutionContext) {
+	backend, err := getBackend(context.GetType())
+	if err != nil {
+		log.Errorf("Archive logs of execution %d error: %s\n", context.GetExecuteSeqID(), err)
+		return
+	}
+	c, err := backend.NewComponent(context.GetExecuteSeqID(), context.GetKubeMaster())
+	if err != nil {
+		log.Errorf("Archive logs of execution %d error: %s\n", context.GetExecuteSeqID(), err)
+		return
+	}
+	if r, ok := c.(resource); ok {
+		archiveLogs(r, context)
+	}
+}
+
+func IsTerminalStatus(status types.ExecutionStatus) bool {
+	return status == types.ComponentExecutionStatusFinished ||
+		status == types.ComponentExecutionStatusFailed ||
+		status == types.ComponentExecutionStatusStoped
 }
 
-func (component *kubeComponent) String() string {
-	return fmt.Sprintf("kubernetes component[%d]", component.SeqID)
+// appendExecutionDetail records line in the detail of a not yet terminated
+// execution.
+func appendExecutionDetail(seqID int64, line string) error {
+	componentExecution, err := model.SelectComponentExecutionForUpdate(seqID)
+	if err != nil {
+		return errors.New("select component execution for update error: " + err.Error())
+	}
+	if IsTerminalStatus(componentExecution.Status) {
+		componentExecution.Rollback()
+		return nil
+	}
+	componentExecution.Detail = componentExecution.Detail +
+		time.Now().Format("2006-01-02 15:04:05") +
+		" " + line + ".\n"
+	return componentExecution.Save()
+}
+
+// transitExecution moves a not yet terminated execution to status, records
+// reason in the detail, archives the logs and notifies the executor.
+func transitExecution(seqID int64, status types.ExecutionStatus, reason string) (ExecutionContext, error) {
+	componentExecution, err := model.SelectComponentExecutionForUpdate(seqID)
+	if err != nil {
+		return nil, errors.New("select component execution for update error: " + err.Error())
+	}
+	if IsTerminalStatus(componentExecution.Status) {
+		componentExecution.Rollback()
+		return nil, fmt.Errorf("component execution status is %s", componentExecution.Status)
+	}
+	componentExecution.Status = status
+	componentExecution.Detail = componentExecution.Detail +
+		time.Now().Format("2006-01-02 15:04:05") +
+		" " + reason + ", status is " + status.String() + ".\n"
+	context := &componentExecutionContext{componentExecution.ComponentExecution}
+	err = notifyExecutor(componentExecution, context)
+	if err != nil {
+		log.Errorln("TransitExecution save component execution error:", err)
+		return nil, errors.New("save component execution error: " + err.Error())
+	}
+	if IsTerminalStatus(status) {
+		archiveExecution(context)
+	}
+	return context, nil
 }
 
-func (component *kubeComponent) Start() {
-	if component.SeqID <= 0 {
-		log.Errorln("Start component invalid sequence id:", component.SeqID)
+func startResource(seqID int64, r resource) {
+	if seqID <= 0 {
+		log.Errorln("Start component invalid sequence id:", seqID)
 		return
 	}
 
-	componentExecution, err := model.SelectComponentExecutionForUpdate(component.SeqID)
+	componentExecution, err := model.SelectComponentExecutionForUpdate(seqID)
 	if err != nil {
 		log.Errorln("Start component select component execution error:", err)
 		return
@@ -194,233 +361,64 @@ func (component *kubeComponent) Start() {
 		log.Errorln("Start Component status is not accepted")
 		return
 	}
-	log.Infof("%s will start executing", component)
-	if componentExecution.Timeout > 0 {
-		go func() {
-			time.Sleep(time.Duration(componentExecution.Timeout) * time.Second)
-			componentExecution, err := model.SelectComponentExecutionForUpdate(component.SeqID)
-			if err != nil {
-				log.Errorln("Component timeout select component execution error:", err)
-				return
-			}
-			if componentExecution.Status == types.ComponentExecutionStatusFinished ||
-				componentExecution.Status == types.ComponentExecutionStatusFailed ||
-				componentExecution.Status == types.ComponentExecutionStatusStoped {
-				componentExecution.Rollback()
-				return
-			}
-			componentExecution.Detail = componentExecution.Detail +
-				time.Now().Format("2006-01-02 15:04:05") +
-				" execution is timeout.\n"
-			err = componentExecution.Save()
-			if err != nil {
-				log.Errorln("Component timeout save component execution error:", err)
-			}
-
-			go component.Stop()
-		}()
-	}
+	log.Infof("%s will start executing", r)
 
 	context := &componentExecutionContext{componentExecution.ComponentExecution}
-	kubeResp, err := component.create(context)
+	resp, err := r.create(context)
 	if err != nil {
-		log.Errorln("Start component send request to kubernetes error:", err)
+		log.Errorf("Start component send request to %s error: %s\n", r.kind(), err)
 		componentExecution.Status = types.ComponentExecutionStatusFailed
 		componentExecution.Detail = componentExecution.Detail +
 			time.Now().Format("2006-01-02 15:04:05") +
-			" failed to create kubernetes resource: " + err.Error() + ", status is failed.\n"
-		data, err := json.Marshal(kubeResp)
+			" failed to create " + r.kind() + " resource: " + err.Error() + ", status is failed.\n"
+		data, err := json.Marshal(resp)
 		if err != nil {
-			log.Errorln("Start component marshal kubeResp error:", err)
+			log.Errorln("Start component marshal resp error:", err)
 		}
 		componentExecution.KubeResp = string(data)
-		err = componentExecution.Save()
+		err = notifyExecutor(componentExecution, context)
 		if err != nil {
 			log.Errorln("Start Component save component execution error:", err)
 		}
-		go component.delete(context)
-
-		component.notifyExecutor(context)
+		go r.delete(context)
 	} else {
-		data, err := json.Marshal(kubeResp)
+		data, err := json.Marshal(resp)
 		if err != nil {
-			log.Errorln("Start component marshal kubeResp error:", err)
+			log.Errorln("Start component marshal resp error:", err)
 		}
 		componentExecution.KubeResp = string(data)
 		componentExecution.Detail = componentExecution.Detail +
 			time.Now().Format("2006-01-02 15:04:05") +
-			" successfully created kubernetes resource, status is accepted.\n"
+			" successfully created " + r.kind() + " resource, status is accepted.\n"
 		err = componentExecution.Save()
 		if err != nil {
 			log.Errorln("Start Component save component execution error:", err)
 		}
-	}
-}
-
-func buildKubeClient(kubeMaster string) (*kubernetes.Clientset, error) {
-	config, err := clientcmd.BuildConfigFromFlags(kubeMaster, "")
-	if err != nil {
-		return nil, err
-	}
-	return kubernetes.NewForConfig(config)
-}
-
-//func (component *kubeComponent) buildKubeClient(kubeMaster string) error {
-//	component.mu.Lock()
-//	defer component.mu.Unlock()
-//	if component.c == nil {
-//		config, err := clientcmd.BuildConfigFromFlags(kubeMaster, "")
-//		if err != nil {
-//			return err
-//		}
-//		component.c, err = kubernetes.NewForConfig(config)
-//		if err != nil {
-//			return err
-//		}
-//	}
-//	return nil
-//}
-
-func (component *kubeComponent) create(context ExecutionContext) (*types.KubeSetting, error) {
-	//err := component.buildKubeClient(context.GetKubeMaster())
-	//if err != nil {
-	//	return nil, errors.New("build kubernetes client error: " + err.Error())
-	//}
-
-	kubeSetting := new(types.KubeSetting)
-	kubeResp := new(types.KubeSetting)
-	err := json.Unmarshal([]byte(context.GetKubeSetting()), kubeSetting)
-	if err != nil {
-		return kubeResp, errors.New("unmarshal KubeSetting error: " + err.Error())
-	}
-	seqID := strconv.FormatInt(context.GetExecuteSeqID(), 10)
-	if kubeSetting.Service != nil {
-		kubeSetting.Service.Name = "co-svc-" + seqID
-		kubeSetting.Service.Namespace = context.GetExecutorName()
-		kubeSetting.Service.Spec.Selector = make(map[string]string)
-		kubeSetting.Service.Spec.Selector["CO_EXECUTE_SEQ_ID"] = seqID
-		kubeResp.Service, err = component.c.CoreV1().Services(kubeSetting.Service.Namespace).Create(kubeSetting.Service)
-		if err != nil {
-			log.Errorf("Create kubernetes service[%v] error: %s", kubeSetting.Service, err)
-			return kubeResp, errors.New("start service error: " + err.Error())
-		}
-	}
-	if kubeSetting.Pod != nil {
-		kubeSetting.Pod.Name = "co-pod-" + seqID
-		kubeSetting.Pod.Namespace = context.GetExecutorName()
-		kubeSetting.Pod.Labels = make(map[string]string)
-		kubeSetting.Pod.Labels["CO_EXECUTE_SEQ_ID"] = seqID
-		kubeSetting.Pod.Spec.RestartPolicy = v1.RestartPolicyOnFailure
-		for i, container := range kubeSetting.Pod.Spec.Containers {
-			if context.GetImageTag() != "" {
-				container.Image = context.GetImageName() + ":" + context.GetImageTag()
-			} else {
-				container.Image = context.GetImageName()
-			}
-			container.Name = fmt.Sprintf("%s-%s-%d", "co-container", seqID, i)
-			//container.ImagePullPolicy = v1.PullAlways
-			container.ImagePullPolicy = v1.PullIfNotPresent
-			for _, env := range context.GetEnvs() {
-				container.Env = append(container.Env, v1.EnvVar{
-					Name: env.Key,
-					Value: env.Value,
-				})
-			}
-			container.Env = append(container.Env, v1.EnvVar{
-				Name: "CO_EXECUTE_SEQ_ID",
-				Value: seqID,
-			}, v1.EnvVar{
-				Name: "CO_EXECUTE_TIMEOUT",
-				Value: strconv.Itoa(context.GetTimeout()),
-			}, v1.EnvVar{
-				Name: "CO_INPUT",
-				Value: context.GetInput(),
-			}, v1.EnvVar{
-				Name: "CO_EVENT_URL",
-				Value: ServiceUrl + "/v2/events",
-			})
-			kubeSetting.Pod.Spec.Containers[i] = container
-		}
-		kubeResp.Pod, err = component.c.CoreV1().Pods(kubeSetting.Pod.Namespace).Create(kubeSetting.Pod)
-		if err != nil {
-			log.Errorf("Create kubernetes pod[%v] error: %s", kubeSetting.Pod, err)
-			return kubeResp, errors.New("start pod error: " + err.Error())
+		if w, ok := r.(watcher); ok {
+			go watchLeased(seqID, w, context)
 		}
 	}
-	return kubeResp, nil
 }
 
-func (component *kubeComponent) notifyExecutor(context ExecutionContext) {
-	if context.GetIsDebug() {
-		value, ok := cache.Get(context.GetExecuteSeqID())
-		if !ok {
-			log.Warnf("Component message channel key %d not exist\n", context.GetExecuteSeqID())
-			return
-		}
-
-		c, ok := value.(chan types.ExecuteComponentMsg)
-		if !ok {
-			log.Errorf("Can't convert type %T to message channel\n", value)
-			return
-		}
-
-		log.Debugf("NotifyExecutor send debug message to channel")
-
-		c <- types.ExecuteComponentMsg{
-			ExecuteSeqID: context.GetExecuteSeqID(),
-			Status:       context.GetStatus(),
-		}
-	} else {
-		notifyUrl := context.GetNotifyUrl()
-		url := notifyUrl.StatusChanged
-		if url != "" {
-			var msg types.ExecuteComponentMsg
-			msg.ExecuteSeqID = context.GetExecuteSeqID()
-			msg.ComponentID = context.GetComponentID()
-			msg.Status = context.GetStatus()
-			msg.Type = context.GetType()
-			msg.ImageName = context.GetImageName()
-			msg.ImageTag = context.GetImageTag()
-			msg.Timeout = context.GetTimeout()
-			msg.KubeMaster = context.GetKubeMaster()
-			kubeSetting := json.RawMessage(context.GetKubeSetting())
-			msg.KubeSetting = &kubeSetting
-			input := json.RawMessage(context.GetInput())
-			msg.Input = &input
-			msg.Envs = context.GetEnvs()
-			msg.NotifyUrl = context.GetNotifyUrl()
-			kubeResp := json.RawMessage(context.GetKubeResp())
-			msg.KubeResp = &kubeResp
-			msg.Detail = context.GetDetail()
-			msg.Events = context.GetEvents()
-
-			body, err := json.Marshal(msg)
-			if err != nil {
-				log.Errorln("NotifyExecutor marshal eventMsg error:", err.Error())
-				return
-			}
-			resp, err := http.Post(url, "application/json", bytes.NewReader(body))
-			if err != nil {
-				log.Errorf("NotifyExecutor send event to %s error: %s\n", url, err)
-				return
-			}
-			defer resp.Body.Close()
-			if resp.StatusCode != http.StatusOK {
-				log.Errorf("NotifyExecutor send event to %s status code: %d\n", url, resp.StatusCode)
-			}
-		} else {
-			log.Warnf("NotifyExecutor execute seq id %d, can't find notify url\n", context.GetExecuteSeqID())
-		}
+func stopResource(seqID int64, r resource) {
+	if seqID <= 0 {
+		log.Errorln("Stop component invalid sequence id:", seqID)
+		return
 	}
-}
 
-func (component *kubeComponent) Stop() {
-	if component.SeqID <= 0 {
-		log.Errorln("Stop component invalid sequence id:", component.SeqID)
+	// The logs are read before the execution is locked, streaming them may
+	// take long.
+	execution, err := model.SelectComponentLogFromID(seqID)
+	if err != nil {
+		log.Errorln("Stop component select component execution error:", err)
 		return
 	}
+	if !execution.Reclaimed && execution.Status != types.ComponentExecutionStatusStoped &&
+		execution.Status != types.ComponentExecutionStatusFailed {
+		archiveLogs(r, &componentExecutionContext{execution})
+	}
 
-	componentExecution, err := model.SelectComponentExecutionForUpdate(component.SeqID)
+	componentExecution, err := model.SelectComponentExecutionForUpdate(seqID)
 	if err != nil {
 		log.Errorln("Stop component select component execution error:", err)
 		return
@@ -431,61 +429,43 @@ func (component *kubeComponent) Stop() {
 		log.Errorln("Stop Component status can't be stoped or failed")
 		return
 	}
-	log.Infof("%s will stop executing", component)
+	log.Infof("%s will stop executing", r)
 
 	context := &componentExecutionContext{componentExecution.ComponentExecution}
-	err = component.delete(context)
+	if componentExecution.Reclaimed {
+		// The reconciler already deleted the resources of the execution.
+		componentExecution.Status = types.ComponentExecutionStatusStoped
+		componentExecution.Detail = componentExecution.Detail +
+			time.Now().Format("2006-01-02 15:04:05") +
+			" " + r.kind() + " resource already deleted, status is stoped.\n"
+		err = notifyExecutor(componentExecution, context)
+		if err != nil {
+			log.Errorln("Stop Component save component execution error:", err)
+		}
+		return
+	}
+	err = r.delete(context)
 	if err != nil {
 		componentExecution.Status = types.ComponentExecutionStatusFailed
 		componentExecution.Detail = componentExecution.Detail +
 			time.Now().Format("2006-01-02 15:04:05") +
-				" failed to delete kubernetes resource: " + err.Error() + ", status is failed.\n"
+			" failed to delete " + r.kind() + " resource: " + err.Error() + ", status is failed.\n"
 	} else {
 		componentExecution.Status = types.ComponentExecutionStatusStoped
+		componentExecution.Reclaimed = true
 		componentExecution.Detail = componentExecution.Detail +
 			time.Now().Format("2006-01-02 15:04:05") +
-			" successfully deleted kubernetes resource, status is stoped.\n"
+			" successfully deleted " + r.kind() + " resource, status is stoped.\n"
 
 	}
-	err = componentExecution.Save()
+	err = notifyExecutor(componentExecution, context)
 	if err != nil {
 		log.Errorln("Stop Component save component execution error:", err)
 	}
-	component.notifyExecutor(context)
-}
-
-func (component *kubeComponent) delete(context ExecutionContext) error {
-	//err := component.buildKubeClient(context.GetKubeMaster())
-	//if err != nil {
-	//	return errors.New("build kube client error: " + err.Error())
-	//}
-	kubeResp := new(types.KubeSetting)
-	err := json.Unmarshal([]byte(context.GetKubeResp()), kubeResp)
-	if err != nil {
-		return errors.New("unmarshal KubeResp error: " + err.Error())
-	}
-	var errs error
-	if kubeResp.Pod != nil {
-		err = component.c.CoreV1().Pods(kubeResp.Pod.Namespace).Delete(kubeResp.Pod.Name, &v1.DeleteOptions{})
-		if err != nil {
-			errs = errors.New("delete pod error: " + err.Error())
-		}
-	}
-	if kubeResp.Service != nil {
-		err = component.c.CoreV1().Services(kubeResp.Service.Namespace).Delete(kubeResp.Service.Name, &v1.DeleteOptions{})
-		if err != nil {
-			if errs != nil {
-				errs = errors.New(errs.Error() + ", delete service error: " + err.Error())
-			} else {
-				errs = errors.New("delete service error: " + err.Error())
-			}
-		}
-	}
-	return errs
 }
 
-func (component *kubeComponent) GetExecutionContext() (ExecutionContext, error) {
-	componentExecution, err := model.SelectComponentLogWithEvents(component.SeqID, true)
+func getExecutionContext(seqID int64) (ExecutionContext, error) {
+	componentExecution, err := model.SelectComponentLogWithEvents(seqID, true)
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, errors.New("get component execution error: " + err.Error())
 	}
@@ -495,15 +475,122 @@ func (component *kubeComponent) GetExecutionContext() (ExecutionContext, error)
 	return &componentExecutionContext{componentExecution}, nil
 }
 
-func GetComponents(name, version string, fuzzy bool, pageNum, versionNum, offset int) ([]model.Component, error) {
+// notifyExecutor saves the status change of an execution along with its
+// status_changed webhook.
+func notifyExecutor(tx executionTx, context ExecutionContext) error {
+	return saveExecution(tx, context, NotificationTypeStatusChanged, context.GetEvents())
+}
+
+// GetComponents returns a page of components along with the total of names,
+// or of versions when name is exact, and the token of the next page which
+// is empty on the last page. Versions are ordered semantically, an exact
+// name pages through its versions and otherwise the first versionNum
+// versions of each name are returned.
+func GetComponents(name, version string, fuzzy bool, pageNum, versionNum, offset int) ([]model.Component, int, string, error) {
 	if name == "" && fuzzy == true {
 		fuzzy = false
 	}
-	components, err := model.SelectComponents(name, version, fuzzy, pageNum, versionNum, offset)
+	components, total, err := model.SelectComponents(name, version, fuzzy, pageNum, offset)
 	if err != nil && err != gorm.ErrRecordNotFound {
-		return nil, errors.New("get components error: " + err.Error())
+		return nil, 0, "", errors.New("get components error: " + err.Error())
+	}
+	size := pageNum
+	byName := name != "" && !fuzzy
+	if byName {
+		size = versionNum
+	}
+	components = pageComponents(components, byName, versionNum, offset)
+	var next string
+	if offset+size < total {
+		next = EncodePageToken(offset + size)
+	}
+	return components, total, next, nil
+}
+
+// EncodePageToken and DecodePageToken convert the offset of a page to an
+// opaque token.
+func EncodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func DecodePageToken(token string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errors.New("invalid page token: " + token)
+	}
+	offset, err := strconv.Atoi(string(data))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid page token: " + token)
+	}
+	return offset, nil
+}
+
+// pageComponents orders components by name and version. The versions of a
+// single component are paged from offset, otherwise the components are
+// already a page of names and only the first versionNum versions of each
+// are kept.
+func pageComponents(components []model.Component, byName bool, versionNum, offset int) []model.Component {
+	sort.Sort(componentsByVersion(components))
+	if byName {
+		if offset > len(components) {
+			offset = len(components)
+		}
+		components = components[offset:]
+		if len(components) > versionNum {
+			components = components[:versionNum]
+		}
+		return components
+	}
+	versions := make(map[string]int)
+	page := make([]model.Component, 0, len(components))
+	for _, component := range components {
+		if versions[component.Name] < versionNum {
+			versions[component.Name]++
+			page = append(page, component)
+		}
+	}
+	return page
+}
+
+// validateComponentType checks the component type is registered and
+// supports what the component kubernetes setting asks for.
+func validateComponentType(component *model.Component) error {
+	if component.Type == "" {
+		component.Type = model.ComponentTypeKubernetes
+	}
+	backend, err := getBackend(component.Type)
+	if err != nil {
+		return err
+	}
+	var kubeSetting types.KubeSetting
+	if component.KubeSetting != "" {
+		if err := json.Unmarshal([]byte(component.KubeSetting), &kubeSetting); err != nil {
+			return errors.New("unmarshal KubeSetting error: " + err.Error())
+		}
+	}
+	if kubeSetting.Service != nil && backend.Capabilities()&CapabilityService == 0 {
+		return fmt.Errorf("component type %s doesn't support service", component.Type)
+	}
+	if kubeSetting.Job != nil && kubeSetting.Pod == nil {
+		return errors.New("should specify pod when component runs as job")
+	}
+	if kubeSetting.Process != nil && !processAllowed() {
+		return errors.New("local process components are disabled, set local.allowprocess to enable")
+	}
+	return nil
+}
+
+// isProcessComponent reports whether component is a local component which
+// runs as a host process, such a component needs no image.
+func isProcessComponent(component *model.Component) bool {
+	if component.Type != model.ComponentTypeLocal {
+		return false
+	}
+	var kubeSetting types.KubeSetting
+	if err := json.Unmarshal([]byte(component.KubeSetting), &kubeSetting); err != nil {
+		return false
 	}
-	return components, nil
+	return kubeSetting.Process != nil
 }
 
 func CreateComponent(component *model.Component) (int64, error) {
@@ -516,23 +603,25 @@ func CreateComponent(component *model.Component) (int64, error) {
 	if component.Version == "" {
 		return 0, errors.New("should specify component version")
 	}
-	if component.ImageName == "" {
+	if err := validateVersion(component.Version); err != nil {
+		return 0, err
+	}
+	if component.ImageName == "" && !isProcessComponent(component) {
 		return 0, errors.New("should specify component image name")
 	}
 	if component.Timeout < 0 {
 		log.Warnln("CreateComponent timeout should ge zero")
 		component.Timeout = 0
 	}
-
-	condition := &model.Component{
-		Name:    component.Name,
-		Version: component.Version,
+	if err := validateComponentType(component); err != nil {
+		return 0, err
 	}
-	if result, err := condition.SelectComponent(); err != nil && err != gorm.ErrRecordNotFound {
-		log.Errorln("CreateComponent query component error:", err.Error())
-		return 0, errors.New("query component error: " + err.Error())
-	} else if result.ID > 0 {
-		return 0, fmt.Errorf("component exists, id is: %d", result.ID)
+	if err := validateSchemas(component.InputSchema, component.OutputSchema); err != nil {
+		return 0, err
+	}
+
+	if err := checkVersionUnique(component.Name, component.Version); err != nil {
+		return 0, err
 	}
 
 	if err := component.Create(); err != nil {
@@ -549,6 +638,9 @@ func SaveComponentAsNewVersion(id int64, version string) (int64, error) {
 	if version == "" {
 		return 0, errors.New("should specify component version")
 	}
+	if err := validateVersion(version); err != nil {
+		return 0, err
+	}
 
 	component, err := model.SelectComponentFromID(id)
 	if err != nil && err != gorm.ErrRecordNotFound {
@@ -559,6 +651,13 @@ func SaveComponentAsNewVersion(id int64, version string) (int64, error) {
 		return 0, errors.New("component not found")
 	}
 
+	if isProcessComponent(component) && !processAllowed() {
+		return 0, errors.New("local process components are disabled, set local.allowprocess to enable")
+	}
+	if err := checkVersionUnique(component.Name, version); err != nil {
+		return 0, err
+	}
+
 	component.ID = 0
 	component.Version = version
 	if err := component.Create(); err != nil {
@@ -590,13 +689,19 @@ func UpdateComponent(id int64, component *model.Component) error {
 	//if id != component.ID {
 	//	return errors.New("component id in path not equals to the id in body")
 	//}
-	if component.ImageName == "" {
+	if component.ImageName == "" && !isProcessComponent(component) {
 		return errors.New("should specify component image name")
 	}
 	if component.Timeout < 0 {
 		log.Warnln("UpdateComponent timeout should ge zero")
 		component.Timeout = 0
 	}
+	if err := validateComponentType(component); err != nil {
+		return err
+	}
+	if err := validateSchemas(component.InputSchema, component.OutputSchema); err != nil {
+		return err
+	}
 
 	old, err := model.SelectComponentFromID(id)
 	if err != nil {
@@ -663,8 +768,8 @@ func DebugComponent(id int64, kubeMaster string, input map[string]interface{}, e
 	if component == nil {
 		return nil, errors.New("component not found")
 	}
-	if component.Type >= len(model.ComponentTypes) {
-		return nil, fmt.Errorf("invalid component type: %d", component.Type)
+	if _, err := getBackend(component.Type); err != nil {
+		return nil, err
 	}
 
 	//component.Input = input
@@ -693,131 +798,122 @@ func validateUrl(rawurl string) error {
 	return nil
 }
 
+// StartComponent creates an execution of component id and starts it. A debug
+// execution returns a subscription to its messages as well, which is made
+// before the execution starts so nothing is missed.
 func StartComponent(id int64, executorName, kubeMaster string, input json.RawMessage, envs []types.Env, notifyUrl types.NotifyUrl,
-		isDebug bool, debugSeqID int64, executeChan chan types.ExecuteComponentMsg) (ExecutionContext, error) {
+		isDebug bool) (ExecutionContext, *Subscription, error) {
 	if id <= 0 {
-		return nil, errors.New("component id should greater than zero")
+		return nil, nil, errors.New("component id should greater than zero")
 	}
 	if executorName == "" {
-		return nil, errors.New("should specify executor name when execute a component")
-	}
-
-	if kubeMaster == "" {
-		return nil, errors.New("should specify kubernetes master when execute a component")
-	}
-	u, err := url.Parse(kubeMaster)
-	if err != nil {
-		return nil, errors.New("parse kubeMaster error: " + err.Error())
-	}
-	if u.Scheme != "http" && u.Scheme != "https" {
-		return nil, errors.New("invalid kubeMaster url scheme: " + u.Scheme)
+		return nil, nil, errors.New("should specify executor name when execute a component")
 	}
 	if err := validateUrl(notifyUrl.StatusChanged); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if err := validateUrl(notifyUrl.ComponentStart); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if err := validateUrl(notifyUrl.ComponentResult); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if err := validateUrl(notifyUrl.ComponentStop); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	component, err := GetComponentByID(id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if component == nil {
-		return nil, errors.New("component not found")
+		return nil, nil, errors.New("component not found")
 	}
-	if component.Type >= len(model.ComponentTypes) {
-		return nil, fmt.Errorf("invalid component type: %d", component.Type)
+	if err := validateDocument("input", component.InputSchema, input); err != nil {
+		return nil, nil, err
 	}
-	if isDebug && debugSeqID > 0 {
-		cache.Remove(debugSeqID)
+	backend, err := getBackend(component.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Debug executions too need an executor registered by an admin.
+	executor, err := GetExecutor(executorName)
+	if err != nil {
+		return nil, nil, err
+	}
+	kubeMaster, err = backend.Prepare(executorName, kubeMaster)
+	if err != nil {
+		return nil, nil, err
 	}
-	switch model.ComponentTypes[component.Type] {
-	case model.ComponentTypeKubernetes:
-		executor, err := model.SelectExecutorFromName(executorName)
-		if err != nil && err != gorm.ErrRecordNotFound {
-			return nil, errors.New("select executor from name error: " + err.Error())
-		}
-		if err == gorm.ErrRecordNotFound {
-			executor = new(model.Executor)
-			executor.Name = executorName
-			executor.Key = ""
-			err = executor.Save()
-			if err != nil {
-				return nil, errors.New("create new executor error: " + err.Error())
-			}
-		}
-
-		client, err := buildKubeClient(kubeMaster)
-		if err != nil {
-			return nil, errors.New("build kubernetes client error: " + err.Error())
-		}
-
-		namespace, _ := client.CoreV1().Namespaces().Get(executorName)
-		//if err != nil {
-		//	return nil, errors.New("get namespace error: " + err.Error())
-		//}
-		if namespace.Name == "" {
-			namespace = new(v1.Namespace)
-			namespace.Name = executorName
-			_, err = client.CoreV1().Namespaces().Create(namespace)
-			if err != nil {
-				return nil, errors.New("create namespace error: " + err.Error())
-			}
-		}
 
-		componentExecution := new(model.ComponentExecution)
-		componentExecution.ExecutorID = executor.ID
-		componentExecution.ComponentID = id
-		componentExecution.Status = types.ComponentExecutionStatusAccepted
-		componentExecution.Type = component.Type
-		componentExecution.Timeout = component.Timeout
-		componentExecution.ImageName = component.ImageName
-		componentExecution.ImageTag = component.ImageTag
-		componentExecution.IsDebug = isDebug
-		componentExecution.KubeMaster = kubeMaster
-		componentExecution.KubeSetting = component.KubeSetting
-		//data, err := json.Marshal(input)
-		//if err != nil {
-		//	return nil, errors.New("marshal input error: " + err.Error())
-		//}
-		componentExecution.Input = string(input)
-		data, err := json.Marshal(envs)
-		if err != nil {
-			return nil, errors.New("marshal envs error: " + err.Error())
-		}
-		componentExecution.Envs = string(data)
-		data, err = json.Marshal(notifyUrl)
-		if err != nil {
-			return nil, errors.New("marshal notifys error: " + err.Error())
-		}
-		componentExecution.NotifyUrl = string(data)
-		componentExecution.KubeResp = "{}"
-		componentExecution.Detail = time.Now().Format("2006-01-02 15:04:05") +
-			" successfully created execution, status is accepted.\n"
+	componentExecution, err := createComponentExecution(executor, component, kubeMaster, input, envs, notifyUrl, isDebug)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := backend.NewComponent(componentExecution.ID, kubeMaster)
+	if err != nil {
+		componentExecution.Status = types.ComponentExecutionStatusFailed
+		componentExecution.Detail = componentExecution.Detail +
+			time.Now().Format("2006-01-02 15:04:05") +
+			" failed to create component: " + err.Error() + ", status is failed.\n"
 		if err := componentExecution.Save(); err != nil {
-			return nil, errors.New("create component log error: " + err.Error())
+			log.Errorln("StartComponent save component execution error:", err)
 		}
+		return nil, nil, err
+	}
 
-		if isDebug {
-			cache.Add(componentExecution.ID, executeChan)
-		}
-		kubeComponent := kubeComponent{
-			SeqID: componentExecution.ID,
-			c: client,
-		}
-		go kubeComponent.Start()
-		return &componentExecutionContext{componentExecution}, nil
-	case model.ComponentTypeMesos, model.ComponentTypeSwarm:
-		return nil, errors.New("currently only kubernetes component supported")
-	default:
-		return nil, errors.New("currently only kubernetes component supported")
+	var subscription *Subscription
+	if isDebug {
+		subscription = SubscribeExecution(componentExecution.ID)
 	}
+	go c.Start()
+	return &componentExecutionContext{componentExecution}, subscription, nil
+}
+
+func createComponentExecution(executor *model.Executor, component *model.Component, kubeMaster string, input json.RawMessage,
+		envs []types.Env, notifyUrl types.NotifyUrl, isDebug bool) (*model.ComponentExecution, error) {
+	componentExecution := new(model.ComponentExecution)
+	componentExecution.ExecutorID = executor.ID
+	componentExecution.ComponentID = component.ID
+	componentExecution.Status = types.ComponentExecutionStatusAccepted
+	componentExecution.Type = component.Type
+	componentExecution.Timeout = component.Timeout
+	if component.Timeout > 0 {
+		deadline := time.Now().Add(time.Duration(component.Timeout) * time.Second)
+		componentExecution.Deadline = &deadline
+	}
+	componentExecution.ImageName = component.ImageName
+	componentExecution.ImageTag = component.ImageTag
+	secret, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+	componentExecution.Secret = secret
+	componentExecution.IsDebug = isDebug
+	componentExecution.KubeMaster = kubeMaster
+	componentExecution.KubeSetting = component.KubeSetting
+	//data, err := json.Marshal(input)
+	//if err != nil {
+	//	return nil, errors.New("marshal input error: " + err.Error())
+	//}
+	componentExecution.Input = string(input)
+	componentExecution.OutputSchema = component.OutputSchema
+	data, err := json.Marshal(envs)
+	if err != nil {
+		return nil, errors.New("marshal envs error: " + err.Error())
+	}
+	componentExecution.Envs = string(data)
+	data, err = json.Marshal(notifyUrl)
+	if err != nil {
+		return nil, errors.New("marshal notifys error: " + err.Error())
+	}
+	componentExecution.NotifyUrl = string(data)
+	componentExecution.KubeResp = "{}"
+	componentExecution.Detail = time.Now().Format("2006-01-02 15:04:05") +
+		" successfully created execution, status is accepted.\n"
+	if err := componentExecution.Save(); err != nil {
+		return nil, errors.New("create component log error: " + err.Error())
+	}
+	return componentExecution, nil
 }
 
 func StopComponent(id int64) error {
@@ -835,23 +931,19 @@ func StopComponent(id int64) error {
 		componentExecution.Status == types.ComponentExecutionStatusFailed {
 		return errors.New("status can't be stoped or failed")
 	}
-	switch model.ComponentTypes[componentExecution.Type] {
-	case model.ComponentTypeKubernetes:
-		client, err := buildKubeClient(componentExecution.KubeMaster)
-		if err != nil {
-			return errors.New("build kubernetes client error: " + err.Error())
-		}
-		kubeComponent := kubeComponent{
-			SeqID: componentExecution.ID,
-			c: client,
-		}
-		go kubeComponent.Stop()
-		return nil
-	case model.ComponentTypeMesos, model.ComponentTypeSwarm:
-		return errors.New("currently only kubernetes component supported")
-	default:
-		return errors.New("currently only kubernetes component supported")
+	backend, err := getBackend(componentExecution.Type)
+	if err != nil {
+		return err
+	}
+	if backend.Capabilities()&CapabilityStop == 0 {
+		return fmt.Errorf("component type %s can't be stoped", componentExecution.Type)
 	}
+	c, err := backend.NewComponent(componentExecution.ID, componentExecution.KubeMaster)
+	if err != nil {
+		return err
+	}
+	go c.Stop()
+	return nil
 }
 
 func GetComponentExecution(id int64, withEvents bool) (ExecutionContext, error) {
@@ -867,3 +959,106 @@ func GetComponentExecution(id int64, withEvents bool) (ExecutionContext, error)
 	}
 	return &componentExecutionContext{componentExecution}, nil
 }
+
+const (
+	defaultExecutionPageSize = 20
+	maxExecutionPageSize     = 100
+)
+
+// ListExecutions returns a page of the executions matching filter, the
+// newest first. cursor is empty for the first page and the returned next
+// cursor otherwise, next is empty on the last page.
+func ListExecutions(filter model.ExecutionFilter, cursor string, limit int) (executions []model.ComponentExecution, next string, err error) {
+	if limit <= 0 {
+		limit = defaultExecutionPageSize
+	}
+	if limit > maxExecutionPageSize {
+		limit = maxExecutionPageSize
+	}
+	var beforeID int64
+	if cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err == nil {
+			beforeID, err = strconv.ParseInt(string(data), 10, 64)
+		}
+		if err != nil || beforeID <= 0 {
+			return nil, "", errors.New("invalid cursor: " + cursor)
+		}
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return nil, "", errors.New("created_after should be before created_before")
+	}
+
+	executions, err = model.SelectComponentExecutions(filter, beforeID, limit+1)
+	if err != nil {
+		return nil, "", errors.New("list component executions error: " + err.Error())
+	}
+	if len(executions) > limit {
+		executions = executions[:limit]
+		next = base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(executions[limit-1].ID, 10)))
+	}
+	return executions, next, nil
+}
+
+// GetExecutionLogs returns the output of execution id. It is read from the
+// cluster while the resources of the execution exist, and from the archive
+// saved before they were deleted otherwise, options.SinceSeconds is ignored
+// by the archive.
+func GetExecutionLogs(id int64, options types.LogOptions) (io.ReadCloser, error) {
+	if id <= 0 {
+		return nil, errors.New("execution id should greater than zero")
+	}
+	componentExecution, err := model.SelectComponentLogWithEvents(id, true)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.New("get component execution error: " + err.Error())
+	}
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.New("component execution not found")
+	}
+	backend, err := getBackend(componentExecution.Type)
+	if err != nil {
+		return nil, err
+	}
+	if backend.Capabilities()&CapabilityLogs == 0 {
+		return nil, fmt.Errorf("component type %s doesn't support logs", componentExecution.Type)
+	}
+
+	executionLog, archiveErr := model.SelectExecutionLog(id)
+	if archiveErr != nil && archiveErr != gorm.ErrRecordNotFound {
+		return nil, errors.New("get execution log error: " + archiveErr.Error())
+	}
+	if archiveErr == nil && IsTerminalStatus(componentExecution.Status) {
+		return archivedLogs(executionLog.Content, options), nil
+	}
+
+	c, err := backend.NewComponent(componentExecution.ID, componentExecution.KubeMaster)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := c.(logger)
+	if !ok {
+		return nil, fmt.Errorf("component type %s doesn't support logs", componentExecution.Type)
+	}
+	stream, err := l.logs(&componentExecutionContext{componentExecution}, options)
+	if err != nil {
+		if archiveErr == nil {
+			return archivedLogs(executionLog.Content, options), nil
+		}
+		return nil, errors.New("read logs error: " + err.Error())
+	}
+	return stream, nil
+}
+
+func archivedLogs(content string, options types.LogOptions) io.ReadCloser {
+	if options.TailLines != nil {
+		lines := strings.SplitAfter(strings.TrimSuffix(content, "\n"), "\n")
+		if tail := int(*options.TailLines); tail < len(lines) {
+			lines = lines[len(lines)-tail:]
+		}
+		content = strings.Join(lines, "")
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+	}
+	return ioutil.NopCloser(strings.NewReader(content))
+}
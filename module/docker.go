@@ -0,0 +1,119 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const dockerApiVersion = "v1.24"
+
+// dockerClient sends requests to the docker engine remote api.
+type dockerClient struct {
+	host string
+	c    *http.Client
+}
+
+func newDockerClient(host string) (*dockerClient, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, errors.New("parse docker host error: " + err.Error())
+	}
+	transport := &http.Transport{
+		ResponseHeaderTimeout: 30 * time.Second,
+	}
+	switch u.Scheme {
+	case "http", "https":
+		host = strings.TrimSuffix(host, "/")
+	case "unix":
+		socket := u.Path
+		transport.Dial = func(network, addr string) (net.Conn, error) {
+			return net.DialTimeout("unix", socket, 30*time.Second)
+		}
+		host = "http://docker"
+	default:
+		return nil, errors.New("invalid docker host url scheme: " + u.Scheme)
+	}
+	return &dockerClient{
+		host: host,
+		c:    &http.Client{Transport: transport},
+	}, nil
+}
+
+func (client *dockerClient) do(method, path string, query url.Values, in, out interface{}) error {
+	u := client.host + "/" + dockerApiVersion + path
+	if len(query) > 0 {
+		u = u + "?" + query.Encode()
+	}
+	return sendJSON(client.c, method, u, in, out)
+}
+
+// statusError is returned by sendJSON for a non 2xx response.
+type statusError struct {
+	code    int
+	message string
+}
+
+func (err *statusError) Error() string {
+	if err.message == "" {
+		return fmt.Sprintf("response code: %d", err.code)
+	}
+	return fmt.Sprintf("response code: %d, message: %s", err.code, err.message)
+}
+
+// isNotFound reports whether err is a 404 response of sendJSON.
+func isNotFound(err error) bool {
+	statusErr, ok := err.(*statusError)
+	return ok && statusErr.code == http.StatusNotFound
+}
+
+// sendJSON sends in as the json body of a request and decodes the json
+// response into out, non 2xx responses are returned as errors carrying
+// the message field of the response body.
+func sendJSON(c *http.Client, method, u string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return errors.New("marshal request error: " + err.Error())
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return errors.New("create request error: " + err.Error())
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return errors.New("send request error: " + err.Error())
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.New("read response body error: " + err.Error())
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		var errResp struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &errResp)
+		return &statusError{code: resp.StatusCode, message: errResp.Message}
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return errors.New("unmarshal response body error: " + err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package module
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeAPIServer records the requests sent to a docker engine or marathon
+// and answers them with handler.
+func fakeAPIServer(handler http.HandlerFunc) (*httptest.Server, *[]*http.Request) {
+	requests := make([]*http.Request, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		handler(w, r)
+	}))
+	return server, &requests
+}
+
+func TestSendJSONError(t *testing.T) {
+	server, _ := fakeAPIServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"no such service"}`))
+	})
+	defer server.Close()
+
+	err := sendJSON(http.DefaultClient, "GET", server.URL+"/services/svc-1", nil, nil)
+	if !isNotFound(err) {
+		t.Fatalf("got error %v, want not found", err)
+	}
+	if want := "response code: 404, message: no such service"; err.Error() != want {
+		t.Errorf("got error %q, want %q", err, want)
+	}
+}
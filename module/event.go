@@ -6,11 +6,53 @@ import (
 	"github.com/sosozhuang/component/model"
 	"github.com/sosozhuang/component/types"
 	"time"
+	"strconv"
+	"crypto/subtle"
 	"net/http"
-	"bytes"
-	"encoding/json"
 )
 
+// HeaderEventToken carries the token of an execution with an event which
+// is not signed.
+const HeaderEventToken = "X-Component-Event-Token"
+
+// VerifyEvent checks an event of execution executeSeqID carries the token
+// of the execution, or is signed by it. A rejected event is audited.
+// Executions created by older releases have no token and are not verified.
+func VerifyEvent(executeSeqID int64, eventType types.EventType, header http.Header, body []byte, remoteAddr string) error {
+	context, err := getExecutionContext(executeSeqID)
+	if err != nil {
+		return err
+	}
+	token := context.GetSecret()
+	if token == "" {
+		return nil
+	}
+	if value := header.Get(HeaderEventToken); value != "" {
+		if subtle.ConstantTimeCompare([]byte(value), []byte(token)) != 1 {
+			err = errors.New("invalid event token")
+		}
+	} else if header.Get(HeaderSignature) != "" {
+		scope := "event/" + strconv.FormatInt(executeSeqID, 10)
+		err = verifySignature(token, scope, header.Get(HeaderTimestamp), header.Get(HeaderNonce),
+			header.Get(HeaderSignature), body)
+	} else {
+		err = errors.New("missing event token")
+	}
+	if err != nil {
+		log.Warnf("Reject %s event of execution %d from %s: %s\n", eventType, executeSeqID, remoteAddr, err)
+		audit := &model.EventAudit{
+			ExecuteSeqID: executeSeqID,
+			Type:         string(eventType),
+			RemoteAddr:   remoteAddr,
+			Reason:       err.Error(),
+		}
+		if err := audit.Save(); err != nil {
+			log.Errorln("VerifyEvent save event audit error:", err)
+		}
+	}
+	return err
+}
+
 func ReceiveEvent(executeSeqID int64, eventType types.EventType, content string) error {
 	execution, err := model.SelectComponentExecutionForUpdate(executeSeqID)
 	if err != nil {
@@ -22,7 +64,9 @@ func ReceiveEvent(executeSeqID int64, eventType types.EventType, content string)
 		Type:         eventType,
 		Content:      content,
 	}
-	err = event.Save()
+	// The event is saved in the transaction holding the execution, a
+	// separate connection may wait for the lock on SQLite.
+	err = execution.SaveEvent(event)
 	if err != nil {
 		execution.Rollback()
 		return errors.New("save event error: " + err.Error())
@@ -39,11 +83,6 @@ func ReceiveEvent(executeSeqID int64, eventType types.EventType, content string)
 		execution.Detail = execution.Detail +
 			time.Now().Format("2006-01-02 15:04:05") +
 			" recevied component_start event, status is started.\n"
-		err = execution.Save()
-		if err != nil {
-			log.Errorln("ReceiveEvent save component execution error:", err)
-
-		}
 	case model.EventTypeComponentResult:
 		if execution.Status != types.ComponentExecutionStatusStarted &&
 			execution.Status != types.ComponentExecutionStatusAccepted {
@@ -51,13 +90,20 @@ func ReceiveEvent(executeSeqID int64, eventType types.EventType, content string)
 			execution.Rollback()
 			return errors.New("component execution status is not started")
 		}
-		execution.Status = types.ComponentExecutionStatusFinished
-		execution.Detail = execution.Detail +
-			time.Now().Format("2006-01-02 15:04:05") +
-			" recevied component_finish event, status is finished.\n"
-		err = execution.Save()
-		if err != nil {
-			log.Errorln("ReceiveEvent save component execution error:", err)
+		if err := validateDocument("output", execution.OutputSchema, []byte(content)); err != nil {
+			execution.Status = types.ComponentExecutionStatusFailed
+			execution.Detail = execution.Detail +
+				time.Now().Format("2006-01-02 15:04:05") +
+				" recevied component_result event, " + err.Error() + ", status is failed.\n"
+		} else {
+			// The output is json only when the component declares its schema.
+			if execution.OutputSchema != "" {
+				execution.Output = content
+			}
+			execution.Status = types.ComponentExecutionStatusFinished
+			execution.Detail = execution.Detail +
+				time.Now().Format("2006-01-02 15:04:05") +
+				" recevied component_finish event, status is finished.\n"
 		}
 	case model.EventTypeComponentStop:
 		if execution.Status != types.ComponentExecutionStatusFinished &&
@@ -71,95 +117,34 @@ func ReceiveEvent(executeSeqID int64, eventType types.EventType, content string)
 		execution.Detail = execution.Detail +
 			time.Now().Format("2006-01-02 15:04:05") +
 			" recevied component_stop event, going to stop execution.\n"
-		err = execution.Save()
-		if err != nil {
-			log.Errorln("ReceiveEvent save component execution error:", err)
-		}
-		err = StopComponent(executeSeqID)
-		if err != nil {
-			return errors.New("stop component error: " + err.Error())
-		}
 	default:
 		log.Warnln("RecevieEvent invalid event type:", string(eventType))
 	}
 	context := &componentExecutionContext{execution.ComponentExecution}
 	eventMsg := types.EventMsg{
+		ID:           event.ID,
 		ExecuteSeqID: event.ExecuteSeqID,
 		Type: eventType,
 		Content: event.Content,
 		CreateAt: event.CreatedAt,
 	}
+	// The event, the status and the webhook of the event are committed
+	// together.
+	err = saveExecution(execution, context, string(eventType), []types.EventMsg{eventMsg})
+	if err != nil {
+		log.Errorln("ReceiveEvent save component execution error:", err)
+		return errors.New("save component execution error: " + err.Error())
+	}
 
-	if context.GetIsDebug() {
-		go func() {
-			value, ok := cache.Get(executeSeqID)
-			if !ok {
-				log.Warnf("Component message channel key %d not exist\n", executeSeqID)
-				return
-			}
-
-			c, ok := value.(chan types.ExecuteComponentMsg)
-			if !ok {
-				log.Errorf("Can't convert type %T to message channel\n", value)
-				return
-			}
-
-			c <- types.ExecuteComponentMsg{
-				ExecuteSeqID: executeSeqID,
-				Status:       execution.Status,
-				Events:       []types.EventMsg{eventMsg},
-			}
-		}()
-	} else {
-		var url string
-		notifyUrl := context.GetNotifyUrl()
-		switch eventType {
-		case model.EventTypeComponentStart:
-			url = notifyUrl.ComponentStart
-		case model.EventTypeComponentResult:
-			url = notifyUrl.ComponentResult
-		case model.EventTypeComponentStop:
-			url = notifyUrl.ComponentStop
-		}
-		if url != "" {
-			go func() {
-				var msg types.ExecuteComponentMsg
-				msg.ExecuteSeqID = context.GetExecuteSeqID()
-				msg.ComponentID = context.GetComponentID()
-				msg.Status = context.GetStatus()
-				msg.Type = context.GetType()
-				msg.ImageName = context.GetImageName()
-				msg.ImageTag = context.GetImageTag()
-				msg.Timeout = context.GetTimeout()
-				msg.KubeMaster = context.GetKubeMaster()
-				kubeSetting := json.RawMessage(context.GetKubeSetting())
-				msg.KubeSetting = &kubeSetting
-				input := json.RawMessage(context.GetInput())
-				msg.Input = &input
-				msg.Envs = context.GetEnvs()
-				msg.NotifyUrl = context.GetNotifyUrl()
-				kubeResp := json.RawMessage(context.GetKubeResp())
-				msg.KubeResp = &kubeResp
-				msg.Detail = context.GetDetail()
-				msg.Events = []types.EventMsg{eventMsg}
-
-				body, err := json.Marshal(msg)
-				if err != nil {
-					log.Errorln("ReceiveEvent marshal eventMsg error:", err.Error())
-					return
-				}
-				resp, err := http.Post(url, "application/json", bytes.NewReader(body))
-				if err != nil {
-					log.Errorf("ReceiveEvent send event to %s error: %s\n", url, err)
-					return
-				}
-				defer resp.Body.Close()
-				if resp.StatusCode != http.StatusOK {
-					log.Errorf("ReceiveEvent send event to %s status code: %d\n", url, resp.StatusCode)
-				}
-			}()
-		} else {
-			log.Warnf("ReceiveEvent execute seq id %d, type %v, can't find notify url\n", executeSeqID, eventType)
+	switch eventType {
+	case model.EventTypeComponentResult:
+		// The result is the last event of a component, the logs are kept
+		// before the reconciler or gc deletes its objects.
+		go archiveExecution(context)
+	case model.EventTypeComponentStop:
+		err = StopComponent(executeSeqID)
+		if err != nil {
+			return errors.New("stop component error: " + err.Error())
 		}
 	}
 	return nil
@@ -0,0 +1,104 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/sosozhuang/component/model"
+	"regexp"
+)
+
+// executorNamePattern is a DNS label, as the executor name is also the
+// kubernetes namespace of its executions.
+var executorNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+func validateExecutorName(name string) error {
+	if name == "" {
+		return errors.New("should specify executor name")
+	}
+	if len(name) > 30 || !executorNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid executor name %s, should be at most 30 lower case alphanumeric characters or '-'", name)
+	}
+	return nil
+}
+
+// RegisterExecutor creates an executor with a new key. An executor revoked
+// before is registered again under a new key.
+func RegisterExecutor(name string) (*model.Executor, error) {
+	if err := validateExecutorName(name); err != nil {
+		return nil, err
+	}
+	executor, err := model.SelectExecutorFromNameUnscoped(name)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.New("select executor from name error: " + err.Error())
+	}
+	if err == nil && executor.DeletedAt == nil {
+		return nil, fmt.Errorf("executor %s already registered", name)
+	}
+	if err == gorm.ErrRecordNotFound {
+		executor = new(model.Executor)
+		executor.Name = name
+	}
+	executor.DeletedAt = nil
+	executor.Key, err = generateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err = executor.SaveUnscoped(); err != nil {
+		return nil, errors.New("save executor error: " + err.Error())
+	}
+	return executor, nil
+}
+
+func GetExecutors() ([]model.Executor, error) {
+	executors, err := model.SelectExecutors()
+	if err != nil {
+		return nil, errors.New("get executors error: " + err.Error())
+	}
+	return executors, nil
+}
+
+// GetExecutor returns the registered executor named name.
+func GetExecutor(name string) (*model.Executor, error) {
+	if name == "" {
+		return nil, errors.New("should specify executor name")
+	}
+	executor, err := model.SelectExecutorFromName(name)
+	if err == gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("executor %s is not registered", name)
+	}
+	if err != nil {
+		return nil, errors.New("select executor from name error: " + err.Error())
+	}
+	return executor, nil
+}
+
+// RotateExecutorKey replaces the key of executor name, webhooks are signed
+// with the new key at once.
+func RotateExecutorKey(name string) (*model.Executor, error) {
+	executor, err := GetExecutor(name)
+	if err != nil {
+		return nil, err
+	}
+	executor.Key, err = generateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err = executor.Save(); err != nil {
+		return nil, errors.New("save executor error: " + err.Error())
+	}
+	return executor, nil
+}
+
+// RevokeExecutor deletes executor name, it can't execute components until
+// it is registered again.
+func RevokeExecutor(name string) error {
+	executor, err := GetExecutor(name)
+	if err != nil {
+		return err
+	}
+	if err = executor.Delete(); err != nil {
+		return errors.New("delete executor error: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package module
+
+import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/containerops/configure"
+	"github.com/jinzhu/gorm"
+	"github.com/sosozhuang/component/model"
+	"github.com/sosozhuang/component/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultGCGrace = 5 * time.Minute
+
+// installationLabel is set on every cluster object an execution creates,
+// only the objects labelled with the id of this installation are collected.
+const installationLabel = "CO_INSTALLATION"
+
+// installationID returns the configured id of this installation, daemons
+// sharing a cluster with another database must use different ids.
+func installationID() string {
+	if id := configure.GetString("daemon.installation"); id != "" {
+		return id
+	}
+	return "containerops"
+}
+
+// GCResource is a cluster object created for a component execution.
+type GCResource struct {
+	Type         types.ComponentType `json:"type"`
+	Master       string              `json:"master"`
+	Kind         string              `json:"kind"`
+	Namespace    string              `json:"namespace,omitempty"`
+	Name         string              `json:"name"`
+	ID           string              `json:"id,omitempty"`
+	ExecuteSeqID int64               `json:"execute_seq_id"`
+	// Reason tells why the object should no longer exist.
+	Reason  string `json:"reason"`
+	Deleted bool   `json:"deleted"`
+	Error   string `json:"error,omitempty"`
+}
+
+func (r GCResource) String() string {
+	name := r.Name
+	if r.Namespace != "" {
+		name = r.Namespace + "/" + name
+	}
+	return fmt.Sprintf("%s %s %s on %s", r.Type, r.Kind, name, r.Master)
+}
+
+// collector is implemented by a backend which can list and delete the
+// objects labelled with CO_EXECUTE_SEQ_ID and the installation id on a
+// master, executors are the names of every executor ever registered,
+// including the revoked ones.
+type collector interface {
+	collect(master string, executors []string) ([]GCResource, error)
+	remove(master string, r GCResource) error
+}
+
+// seqIDFromLabels returns the execution sequence id an object is labelled
+// with, objects of other installations have none.
+func seqIDFromLabels(labels map[string]string) (int64, bool) {
+	if labels[installationLabel] != installationID() {
+		return 0, false
+	}
+	value, ok := labels["CO_EXECUTE_SEQ_ID"]
+	if !ok {
+		return 0, false
+	}
+	seqID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return seqID, true
+}
+
+// legacySeqID returns the execution sequence id of an object created before
+// objects were labelled with the installation id. Such an object is only
+// recognised by the name prefix it was created with, followed by the
+// sequence id.
+func legacySeqID(name, prefix string, labels map[string]string) (int64, bool) {
+	if _, ok := labels[installationLabel]; ok {
+		return 0, false
+	}
+	if !strings.HasPrefix(name, prefix) {
+		return 0, false
+	}
+	seqID, err := strconv.ParseInt(strings.TrimPrefix(name, prefix), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if value, ok := labels["CO_EXECUTE_SEQ_ID"]; ok && value != strconv.FormatInt(seqID, 10) {
+		return 0, false
+	}
+	return seqID, true
+}
+
+// CollectGarbage lists the objects created by this installation on every
+// master used by an execution, and deletes the ones whose execution is
+// terminated for longer than the grace period. The objects of executions
+// missing from the database are only deleted if orphans is true. Nothing is
+// deleted if dryRun is true, the returned resources report what was or would
+// be deleted.
+func CollectGarbage(dryRun, orphans bool) ([]GCResource, error) {
+	grace := configDuration("gc.grace", defaultGCGrace)
+	masters, err := model.SelectExecutionMasters()
+	if err != nil {
+		return nil, fmt.Errorf("select execution masters error: %s", err)
+	}
+	// Revoked executors may still have objects in their namespace.
+	registered, err := model.SelectExecutorsUnscoped()
+	if err != nil {
+		return nil, fmt.Errorf("select executors error: %s", err)
+	}
+	executors := make([]string, 0, len(registered))
+	for _, executor := range registered {
+		executors = append(executors, executor.Name)
+	}
+
+	executions := make(map[int64]*model.ComponentExecution)
+	archived := make(map[int64]bool)
+	garbage := make([]GCResource, 0)
+	for _, master := range masters {
+		backend, ok := GetBackend(master.Type)
+		if !ok {
+			continue
+		}
+		c, ok := backend.(collector)
+		if !ok {
+			continue
+		}
+		resources, err := c.collect(master.KubeMaster, executors)
+		if err != nil {
+			log.Errorf("Collect garbage of %s on %s error: %s\n", master.Type, master.KubeMaster, err)
+			continue
+		}
+		for _, r := range resources {
+			r.Type = master.Type
+			r.Master = master.KubeMaster
+			execution, reason, err := garbageReason(executions, r.ExecuteSeqID, grace, orphans)
+			if err != nil {
+				log.Errorf("Collect garbage of %s error: %s\n", r, err)
+				continue
+			}
+			if reason == "" {
+				continue
+			}
+			r.Reason = reason
+			if !dryRun {
+				if execution != nil && !execution.Reclaimed && !archived[execution.ID] {
+					archived[execution.ID] = true
+					archiveExecution(&componentExecutionContext{execution})
+				}
+				if err := c.remove(master.KubeMaster, r); err != nil {
+					r.Error = err.Error()
+					log.Errorf("Collect garbage delete %s error: %s\n", r, err)
+				} else {
+					r.Deleted = true
+					log.Infof("Collect garbage deleted %s: %s\n", r, reason)
+					if execution != nil {
+						if err := model.MarkExecutionReclaimed(execution.ID); err != nil {
+							log.Errorf("Collect garbage mark execution %d reclaimed error: %s\n", execution.ID, err)
+						}
+					}
+				}
+			}
+			garbage = append(garbage, r)
+		}
+	}
+	return garbage, nil
+}
+
+// garbageReason returns the execution seqID and why its objects should no
+// longer exist, or an empty reason if they should be kept. The execution is
+// nil if it is missing from the database.
+func garbageReason(executions map[int64]*model.ComponentExecution, seqID int64, grace time.Duration, orphans bool) (*model.ComponentExecution, string, error) {
+	execution, ok := executions[seqID]
+	if !ok {
+		var err error
+		execution, err = model.SelectComponentLogFromID(seqID)
+		if err == gorm.ErrRecordNotFound {
+			execution = nil
+		} else if err != nil {
+			return nil, "", err
+		}
+		executions[seqID] = execution
+	}
+	if execution == nil {
+		if !orphans {
+			return nil, "", nil
+		}
+		return nil, fmt.Sprintf("execution %d not found", seqID), nil
+	}
+	if IsTerminalStatus(execution.Status) && time.Since(execution.UpdatedAt) >= grace {
+		return execution, fmt.Sprintf("execution %d is %s", seqID, execution.Status), nil
+	}
+	return execution, "", nil
+}
+
+// StartGarbageCollector collects garbage on the configured interval, it
+// returns at once if no interval is configured.
+func StartGarbageCollector() {
+	if configure.GetString("gc.interval") == "" {
+		return
+	}
+	ticker := time.NewTicker(configDuration("gc.interval", time.Hour))
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := CollectGarbage(false, configure.GetBool("gc.orphans")); err != nil {
+			log.Errorln("Collect garbage error:", err)
+		}
+	}
+}
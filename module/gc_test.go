@@ -0,0 +1,33 @@
+package module
+
+import (
+	"testing"
+)
+
+func TestGarbageSeqID(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		labels map[string]string
+		seqID  int64
+		ok     bool
+	}{
+		{"co-pod-7", "co-pod-", map[string]string{"CO_EXECUTE_SEQ_ID": "7", installationLabel: installationID()}, 7, true},
+		{"co-pod-7", "co-pod-", map[string]string{"CO_EXECUTE_SEQ_ID": "7", installationLabel: "other"}, 0, false},
+		// Legacy objects carry no installation label.
+		{"co-pod-7", "co-pod-", map[string]string{"CO_EXECUTE_SEQ_ID": "7"}, 7, true},
+		{"co-pod-7", "co-pod-", map[string]string{"CO_EXECUTE_SEQ_ID": "8"}, 0, false},
+		{"co-svc-7", "co-svc-", nil, 7, true},
+		{"my-pod-7", "co-pod-", map[string]string{"CO_EXECUTE_SEQ_ID": "7"}, 0, false},
+		{"co-svc-x", "co-svc-", nil, 0, false},
+	}
+	for _, test := range tests {
+		seqID, ok := seqIDFromLabels(test.labels)
+		if !ok {
+			seqID, ok = legacySeqID(test.name, test.prefix, test.labels)
+		}
+		if seqID != test.seqID || ok != test.ok {
+			t.Errorf("%s %v: got %d, %v, want %d, %v", test.name, test.labels, seqID, ok, test.seqID, test.ok)
+		}
+	}
+}
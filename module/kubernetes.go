@@ -0,0 +1,708 @@
+package module
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/sosozhuang/component/model"
+	"github.com/sosozhuang/component/types"
+	"io"
+	"k8s.io/client-go/kubernetes"
+	apierrors "k8s.io/client-go/pkg/api/errors"
+	"k8s.io/client-go/pkg/api/v1"
+	batchv1 "k8s.io/client-go/pkg/apis/batch/v1"
+	"k8s.io/client-go/pkg/watch"
+	"k8s.io/client-go/tools/clientcmd"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	jobPollInterval       = 5 * time.Second
+	podWatchRetryInterval = 5 * time.Second
+
+	eventTokenVolume    = "co-event-token"
+	eventTokenMountPath = "/var/run/containerops"
+	eventTokenKey       = "event-token"
+)
+
+func init() {
+	RegisterBackend(model.ComponentTypeKubernetes, new(kubeBackend))
+}
+
+type kubeBackend struct{}
+
+func (backend *kubeBackend) Capabilities() Capability {
+	return CapabilityService | CapabilityLogs | CapabilityStop
+}
+
+func (backend *kubeBackend) Prepare(executorName, master string) (string, error) {
+	if err := validateMaster(master); err != nil {
+		return "", err
+	}
+	client, err := buildKubeClient(master)
+	if err != nil {
+		return "", errors.New("build kubernetes client error: " + err.Error())
+	}
+
+	namespace, _ := client.CoreV1().Namespaces().Get(executorName)
+	//if err != nil {
+	//	return nil, errors.New("get namespace error: " + err.Error())
+	//}
+	if namespace.Name == "" {
+		namespace = new(v1.Namespace)
+		namespace.Name = executorName
+		_, err = client.CoreV1().Namespaces().Create(namespace)
+		if err != nil {
+			return "", errors.New("create namespace error: " + err.Error())
+		}
+	}
+	return master, nil
+}
+
+func (backend *kubeBackend) NewComponent(seqID int64, master string) (Component, error) {
+	client, err := buildKubeClient(master)
+	if err != nil {
+		return nil, errors.New("build kubernetes client error: " + err.Error())
+	}
+	return &kubeComponent{
+		SeqID: seqID,
+		c:     client,
+	}, nil
+}
+
+func (backend *kubeBackend) collect(master string, executors []string) ([]GCResource, error) {
+	client, err := buildKubeClient(master)
+	if err != nil {
+		return nil, errors.New("build kubernetes client error: " + err.Error())
+	}
+	resources := make([]GCResource, 0)
+	options := v1.ListOptions{LabelSelector: "CO_EXECUTE_SEQ_ID," + installationLabel + "=" + installationID()}
+	// Pods created before the installation label only carry
+	// CO_EXECUTE_SEQ_ID, and such services carry no label at all.
+	podOptions := v1.ListOptions{LabelSelector: "CO_EXECUTE_SEQ_ID"}
+	// Executions only create objects in the namespace named after their
+	// executor.
+	for _, namespace := range executors {
+		jobs, err := client.BatchV1().Jobs(namespace).List(options)
+		if err != nil {
+			return nil, errors.New("list jobs error: " + err.Error())
+		}
+		for _, job := range jobs.Items {
+			if seqID, ok := seqIDFromLabels(job.Labels); ok {
+				resources = append(resources, GCResource{Kind: "job", Namespace: job.Namespace, Name: job.Name, ExecuteSeqID: seqID})
+			}
+		}
+		pods, err := client.CoreV1().Pods(namespace).List(podOptions)
+		if err != nil {
+			return nil, errors.New("list pods error: " + err.Error())
+		}
+		for _, pod := range pods.Items {
+			seqID, ok := seqIDFromLabels(pod.Labels)
+			if !ok {
+				seqID, ok = legacySeqID(pod.Name, "co-pod-", pod.Labels)
+			}
+			if ok {
+				resources = append(resources, GCResource{Kind: "pod", Namespace: pod.Namespace, Name: pod.Name, ExecuteSeqID: seqID})
+			}
+		}
+		secrets, err := client.CoreV1().Secrets(namespace).List(options)
+		if err != nil {
+			return nil, errors.New("list secrets error: " + err.Error())
+		}
+		for _, secret := range secrets.Items {
+			if seqID, ok := seqIDFromLabels(secret.Labels); ok {
+				resources = append(resources, GCResource{Kind: "secret", Namespace: secret.Namespace, Name: secret.Name, ExecuteSeqID: seqID})
+			}
+		}
+		services, err := client.CoreV1().Services(namespace).List(v1.ListOptions{})
+		if err != nil {
+			return nil, errors.New("list services error: " + err.Error())
+		}
+		for _, service := range services.Items {
+			seqID, ok := seqIDFromLabels(service.Labels)
+			if !ok {
+				seqID, ok = legacySeqID(service.Name, "co-svc-", service.Labels)
+			}
+			if ok {
+				resources = append(resources, GCResource{Kind: "service", Namespace: service.Namespace, Name: service.Name, ExecuteSeqID: seqID})
+			}
+		}
+	}
+	return resources, nil
+}
+
+func (backend *kubeBackend) remove(master string, r GCResource) error {
+	client, err := buildKubeClient(master)
+	if err != nil {
+		return errors.New("build kubernetes client error: " + err.Error())
+	}
+	switch r.Kind {
+	case "job":
+		orphanDependents := false
+		err = client.BatchV1().Jobs(r.Namespace).Delete(r.Name, &v1.DeleteOptions{OrphanDependents: &orphanDependents})
+	case "pod":
+		err = client.CoreV1().Pods(r.Namespace).Delete(r.Name, &v1.DeleteOptions{})
+	case "service":
+		err = client.CoreV1().Services(r.Namespace).Delete(r.Name, &v1.DeleteOptions{})
+	case "secret":
+		err = client.CoreV1().Secrets(r.Namespace).Delete(r.Name, &v1.DeleteOptions{})
+	default:
+		return fmt.Errorf("invalid kubernetes resource kind: %s", r.Kind)
+	}
+	// The pods of a job may be gone with the job.
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+type kubeComponent struct {
+	SeqID int64
+	c     *kubernetes.Clientset
+	//mu    sync.Mutex
+}
+
+func (component *kubeComponent) String() string {
+	return fmt.Sprintf("kubernetes component[%d]", component.SeqID)
+}
+
+func (component *kubeComponent) Start() {
+	startResource(component.SeqID, component)
+}
+
+func buildKubeClient(kubeMaster string) (*kubernetes.Clientset, error) {
+	config, err := clientcmd.BuildConfigFromFlags(kubeMaster, "")
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+//func (component *kubeComponent) buildKubeClient(kubeMaster string) error {
+//	component.mu.Lock()
+//	defer component.mu.Unlock()
+//	if component.c == nil {
+//		config, err := clientcmd.BuildConfigFromFlags(kubeMaster, "")
+//		if err != nil {
+//			return err
+//		}
+//		component.c, err = kubernetes.NewForConfig(config)
+//		if err != nil {
+//			return err
+//		}
+//	}
+//	return nil
+//}
+
+func (component *kubeComponent) kind() string {
+	return "kubernetes"
+}
+
+func (component *kubeComponent) create(context ExecutionContext) (interface{}, error) {
+	//err := component.buildKubeClient(context.GetKubeMaster())
+	//if err != nil {
+	//	return nil, errors.New("build kubernetes client error: " + err.Error())
+	//}
+
+	kubeSetting := new(types.KubeSetting)
+	kubeResp := new(types.KubeResp)
+	err := json.Unmarshal([]byte(context.GetKubeSetting()), kubeSetting)
+	if err != nil {
+		return kubeResp, errors.New("unmarshal KubeSetting error: " + err.Error())
+	}
+	seqID := strconv.FormatInt(context.GetExecuteSeqID(), 10)
+	if kubeSetting.Service != nil {
+		kubeSetting.Service.Name = "co-svc-" + seqID
+		kubeSetting.Service.Namespace = context.GetExecutorName()
+		if kubeSetting.Service.Labels == nil {
+			kubeSetting.Service.Labels = make(map[string]string)
+		}
+		kubeSetting.Service.Labels["CO_EXECUTE_SEQ_ID"] = seqID
+		kubeSetting.Service.Labels[installationLabel] = installationID()
+		kubeSetting.Service.Spec.Selector = make(map[string]string)
+		kubeSetting.Service.Spec.Selector["CO_EXECUTE_SEQ_ID"] = seqID
+		kubeResp.Service, err = component.c.CoreV1().Services(kubeSetting.Service.Namespace).Create(kubeSetting.Service)
+		if err != nil {
+			log.Errorf("Create kubernetes service[%v] error: %s", kubeSetting.Service, err)
+			return kubeResp, errors.New("start service error: " + err.Error())
+		}
+	}
+	if kubeSetting.Pod != nil && context.GetSecret() != "" {
+		secret := new(v1.Secret)
+		secret.Name = "co-event-" + seqID
+		secret.Namespace = context.GetExecutorName()
+		secret.Labels = map[string]string{"CO_EXECUTE_SEQ_ID": seqID, installationLabel: installationID()}
+		secret.Data = map[string][]byte{eventTokenKey: []byte(context.GetSecret())}
+		_, err = component.c.CoreV1().Secrets(secret.Namespace).Create(secret)
+		if err != nil {
+			log.Errorf("Create kubernetes secret %s error: %s", secret.Name, err)
+			return kubeResp, errors.New("create event token secret error: " + err.Error())
+		}
+		// Only the name is kept, the token must not show in the execution.
+		kubeResp.Secret = new(v1.Secret)
+		kubeResp.Secret.Name = secret.Name
+		kubeResp.Secret.Namespace = secret.Namespace
+	}
+	if kubeSetting.Pod != nil && kubeSetting.Job != nil {
+		job := new(batchv1.Job)
+		job.Name = "co-job-" + seqID
+		job.Namespace = context.GetExecutorName()
+		job.Labels = make(map[string]string)
+		job.Labels["CO_EXECUTE_SEQ_ID"] = seqID
+		job.Labels[installationLabel] = installationID()
+		if context.GetTimeout() > 0 {
+			activeDeadlineSeconds := int64(context.GetTimeout())
+			job.Spec.ActiveDeadlineSeconds = &activeDeadlineSeconds
+		}
+		job.Spec.Template.Labels = make(map[string]string)
+		job.Spec.Template.Labels["CO_EXECUTE_SEQ_ID"] = seqID
+		job.Spec.Template.Labels[installationLabel] = installationID()
+		job.Spec.Template.Spec = kubeSetting.Pod.Spec
+		// Every failed attempt is a new pod, so the job status counts the
+		// failures which the backoff limit is checked against.
+		job.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyNever
+		setContainers(&job.Spec.Template.Spec, context)
+		kubeResp.Job, err = component.c.BatchV1().Jobs(job.Namespace).Create(job)
+		if err != nil {
+			log.Errorf("Create kubernetes job[%v] error: %s", job, err)
+			return kubeResp, errors.New("start job error: " + err.Error())
+		}
+	} else if kubeSetting.Pod != nil {
+		kubeSetting.Pod.Name = "co-pod-" + seqID
+		kubeSetting.Pod.Namespace = context.GetExecutorName()
+		kubeSetting.Pod.Labels = make(map[string]string)
+		kubeSetting.Pod.Labels["CO_EXECUTE_SEQ_ID"] = seqID
+		kubeSetting.Pod.Labels[installationLabel] = installationID()
+		kubeSetting.Pod.Spec.RestartPolicy = v1.RestartPolicyOnFailure
+		setContainers(&kubeSetting.Pod.Spec, context)
+		kubeResp.Pod, err = component.c.CoreV1().Pods(kubeSetting.Pod.Namespace).Create(kubeSetting.Pod)
+		if err != nil {
+			log.Errorf("Create kubernetes pod[%v] error: %s", kubeSetting.Pod, err)
+			return kubeResp, errors.New("start pod error: " + err.Error())
+		}
+	}
+	return kubeResp, nil
+}
+
+// setContainers sets the image, name and envs of every container in spec
+// from the execution. The event token is mounted from the secret of the
+// execution instead of being an env, which anyone reading the pod could see.
+func setContainers(spec *v1.PodSpec, context ExecutionContext) {
+	seqID := strconv.FormatInt(context.GetExecuteSeqID(), 10)
+	if context.GetSecret() != "" {
+		spec.Volumes = append(spec.Volumes, v1.Volume{
+			Name: eventTokenVolume,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{SecretName: "co-event-" + seqID},
+			},
+		})
+	}
+	for i, container := range spec.Containers {
+		if context.GetImageTag() != "" {
+			container.Image = context.GetImageName() + ":" + context.GetImageTag()
+		} else {
+			container.Image = context.GetImageName()
+		}
+		container.Name = fmt.Sprintf("%s-%s-%d", "co-container", seqID, i)
+		//container.ImagePullPolicy = v1.PullAlways
+		container.ImagePullPolicy = v1.PullIfNotPresent
+		for _, env := range executionEnvs(context) {
+			if env.Key == eventTokenEnv {
+				continue
+			}
+			container.Env = append(container.Env, v1.EnvVar{
+				Name:  env.Key,
+				Value: env.Value,
+			})
+		}
+		if context.GetSecret() != "" {
+			container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+				Name:      eventTokenVolume,
+				MountPath: eventTokenMountPath,
+				ReadOnly:  true,
+			})
+			container.Env = append(container.Env, v1.EnvVar{
+				Name:  eventTokenEnv + "_FILE",
+				Value: eventTokenMountPath + "/" + eventTokenKey,
+			})
+		}
+		spec.Containers[i] = container
+	}
+}
+
+// watch follows the pods and the job of an execution until it terminates,
+// so an execution whose container never sends component_result still ends.
+func (component *kubeComponent) watch(context ExecutionContext) {
+	kubeSetting := new(types.KubeSetting)
+	kubeResp := new(types.KubeResp)
+	if err := json.Unmarshal([]byte(context.GetKubeSetting()), kubeSetting); err != nil {
+		log.Errorln("Watch component unmarshal KubeSetting error:", err)
+		return
+	}
+	if err := json.Unmarshal([]byte(context.GetKubeResp()), kubeResp); err != nil {
+		log.Errorln("Watch component unmarshal KubeResp error:", err)
+		return
+	}
+	if kubeResp.Job != nil && kubeSetting.Job != nil {
+		go component.trackJob(kubeResp.Job.Namespace, kubeResp.Job.Name, kubeSetting.Job.BackoffLimit)
+		component.watchPods(kubeResp.Job.Namespace, true)
+	} else if kubeResp.Pod != nil {
+		component.watchPods(kubeResp.Pod.Namespace, false)
+	}
+}
+
+// fatalWaitingReasons are the container waiting reasons which retrying
+// won't recover from.
+var fatalWaitingReasons = map[string]bool{
+	"ImagePullBackOff":           true,
+	"ErrImageNeverPull":          true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CrashLoopBackOff":           true,
+}
+
+// watchPods watches the pods labelled with the execution sequence id and
+// maps their phases and container states to the execution status, pod
+// failures of a job are left to the job backoff limit.
+func (component *kubeComponent) watchPods(namespace string, job bool) {
+	options := v1.ListOptions{
+		LabelSelector: "CO_EXECUTE_SEQ_ID=" + strconv.FormatInt(component.SeqID, 10),
+	}
+	reported := make(map[string]bool)
+	for !component.terminated() {
+		w, err := component.c.CoreV1().Pods(namespace).Watch(options)
+		if err != nil {
+			log.Errorf("Watch pods of %s error: %s\n", component, err)
+			time.Sleep(podWatchRetryInterval)
+			continue
+		}
+		done := component.handlePodEvents(w.ResultChan(), job, reported)
+		w.Stop()
+		if done {
+			return
+		}
+	}
+}
+
+func (component *kubeComponent) handlePodEvents(events <-chan watch.Event, job bool, reported map[string]bool) bool {
+	for event := range events {
+		if event.Type == watch.Error {
+			log.Errorf("Watch pods of %s received error event: %v\n", component, event.Object)
+			return false
+		}
+		pod, ok := event.Object.(*v1.Pod)
+		if !ok {
+			continue
+		}
+		if event.Type == watch.Deleted {
+			if component.terminated() {
+				return true
+			}
+			component.report(reported, "kubernetes pod "+pod.Name+" deleted")
+			continue
+		}
+
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if waiting := containerStatus.State.Waiting; waiting != nil && waiting.Reason != "" {
+				line := fmt.Sprintf("kubernetes pod %s container %s is waiting: %s",
+					pod.Name, containerStatus.Name, waiting.Reason)
+				if fatalWaitingReasons[waiting.Reason] {
+					component.fail(line + " " + waiting.Message)
+					return true
+				}
+				component.report(reported, line)
+			}
+			for _, terminated := range []*v1.ContainerStateTerminated{
+				containerStatus.State.Terminated,
+				containerStatus.LastTerminationState.Terminated,
+			} {
+				if terminated == nil {
+					continue
+				}
+				line := fmt.Sprintf("kubernetes pod %s container %s terminated: %s, exit code %d",
+					pod.Name, containerStatus.Name, terminated.Reason, terminated.ExitCode)
+				if terminated.Reason == "OOMKilled" && !job {
+					component.fail(line)
+					return true
+				}
+				component.report(reported, line)
+			}
+		}
+
+		switch pod.Status.Phase {
+		case v1.PodFailed:
+			line := fmt.Sprintf("kubernetes pod %s failed: %s %s", pod.Name, pod.Status.Reason, pod.Status.Message)
+			if !job {
+				component.fail(line)
+				return true
+			}
+			component.report(reported, line)
+		case v1.PodSucceeded:
+			if !job {
+				component.finish("kubernetes pod " + pod.Name + " succeeded")
+				return true
+			}
+			component.report(reported, "kubernetes pod "+pod.Name+" succeeded")
+		case v1.PodRunning:
+			component.report(reported, "kubernetes pod "+pod.Name+" is running")
+		}
+	}
+	return false
+}
+
+func (component *kubeComponent) terminated() bool {
+	context, err := getExecutionContext(component.SeqID)
+	if err != nil {
+		log.Errorf("Get execution context of %s error: %s\n", component, err)
+		return true
+	}
+	return IsTerminalStatus(context.GetStatus())
+}
+
+// report appends line to the execution detail once.
+func (component *kubeComponent) report(reported map[string]bool, line string) {
+	if reported[line] {
+		return
+	}
+	reported[line] = true
+	if err := appendExecutionDetail(component.SeqID, line); err != nil {
+		log.Errorf("Append detail of %s error: %s\n", component, err)
+	}
+}
+
+// finish marks the execution finished, which archives its logs.
+func (component *kubeComponent) finish(reason string) {
+	if _, err := transitExecution(component.SeqID, types.ComponentExecutionStatusFinished, reason); err != nil {
+		log.Warnf("Finish %s error: %s\n", component, err)
+	}
+}
+
+// fail marks the execution failed and deletes its kubernetes resources,
+// which otherwise keep retrying.
+func (component *kubeComponent) fail(reason string) {
+	context, err := transitExecution(component.SeqID, types.ComponentExecutionStatusFailed, reason)
+	if err != nil {
+		log.Warnf("Fail %s error: %s\n", component, err)
+		return
+	}
+	if err := component.delete(context); err != nil {
+		log.Errorf("Delete kubernetes resource of %s error: %s\n", component, err)
+		return
+	}
+	if err := model.MarkExecutionReclaimed(component.SeqID); err != nil {
+		log.Errorf("Mark %s reclaimed error: %s\n", component, err)
+	}
+}
+
+func (component *kubeComponent) trackJob(namespace, name string, backoffLimit int32) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if component.terminated() {
+			return
+		}
+
+		job, err := component.c.BatchV1().Jobs(namespace).Get(name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				transitExecution(component.SeqID, types.ComponentExecutionStatusFailed,
+					"kubernetes job "+name+" not found")
+				return
+			}
+			log.Errorf("Track job %s/%s error: %s\n", namespace, name, err)
+			continue
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != v1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				component.finish("kubernetes job " + name + " completed")
+				return
+			case batchv1.JobFailed:
+				component.fail("kubernetes job " + name + " failed: " + condition.Reason + " " + condition.Message)
+				return
+			}
+		}
+		// The vendored batch/v1 api has no backoffLimit field, the limit is
+		// enforced here by deleting the job once too many pods failed.
+		if job.Status.Failed > backoffLimit {
+			component.fail(fmt.Sprintf("kubernetes job %s reached backoff limit %d", name, backoffLimit))
+			return
+		}
+	}
+}
+
+// logs streams the output of the pods of the execution, the latest pod of a
+// job comes last.
+func (component *kubeComponent) logs(context ExecutionContext, options types.LogOptions) (io.ReadCloser, error) {
+	kubeResp := new(types.KubeResp)
+	if err := json.Unmarshal([]byte(context.GetKubeResp()), kubeResp); err != nil {
+		return nil, errors.New("unmarshal KubeResp error: " + err.Error())
+	}
+	var pods []v1.Pod
+	if kubeResp.Job != nil {
+		list, err := component.c.CoreV1().Pods(kubeResp.Job.Namespace).List(v1.ListOptions{
+			LabelSelector: "CO_EXECUTE_SEQ_ID=" + strconv.FormatInt(component.SeqID, 10),
+		})
+		if err != nil {
+			return nil, errors.New("list pods error: " + err.Error())
+		}
+		pods = list.Items
+		sort.Sort(podsByCreation(pods))
+	} else if kubeResp.Pod != nil {
+		pod, err := component.c.CoreV1().Pods(kubeResp.Pod.Namespace).Get(kubeResp.Pod.Name)
+		if err != nil {
+			return nil, errors.New("get pod error: " + err.Error())
+		}
+		pods = append(pods, *pod)
+	}
+	if len(pods) == 0 {
+		return nil, errors.New("no pod found")
+	}
+
+	logOptions := &v1.PodLogOptions{
+		Container:    options.Container,
+		Follow:       options.Follow,
+		TailLines:    options.TailLines,
+		SinceSeconds: options.SinceSeconds,
+	}
+	if options.Follow || options.Container != "" {
+		// Only one stream can be followed, it is the latest pod.
+		pod := pods[len(pods)-1]
+		if logOptions.Container == "" && len(pod.Spec.Containers) > 0 {
+			logOptions.Container = pod.Spec.Containers[0].Name
+		}
+		return component.c.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, logOptions).Stream()
+	}
+
+	streams := new(multiReadCloser)
+	var readers []io.Reader
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			containerOptions := *logOptions
+			containerOptions.Container = container.Name
+			stream, err := component.c.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &containerOptions).Stream()
+			if err != nil {
+				streams.Close()
+				return nil, fmt.Errorf("read logs of pod %s container %s error: %s", pod.Name, container.Name, err)
+			}
+			streams.closers = append(streams.closers, stream)
+			readers = append(readers, strings.NewReader("==> "+pod.Name+"/"+container.Name+" <==\n"), stream)
+		}
+	}
+	if len(streams.closers) == 1 {
+		return streams.closers[0].(io.ReadCloser), nil
+	}
+	streams.Reader = io.MultiReader(readers...)
+	return streams, nil
+}
+
+type podsByCreation []v1.Pod
+
+func (pods podsByCreation) Len() int      { return len(pods) }
+func (pods podsByCreation) Swap(i, j int) { pods[i], pods[j] = pods[j], pods[i] }
+func (pods podsByCreation) Less(i, j int) bool {
+	return pods[i].CreationTimestamp.Time.Before(pods[j].CreationTimestamp.Time)
+}
+
+// multiReadCloser reads the logs of several containers one after another.
+type multiReadCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *multiReadCloser) Close() error {
+	var errs error
+	for _, closer := range r.closers {
+		if err := closer.Close(); err != nil {
+			errs = err
+		}
+	}
+	return errs
+}
+
+func (component *kubeComponent) exists(context ExecutionContext) (bool, error) {
+	kubeResp := new(types.KubeResp)
+	err := json.Unmarshal([]byte(context.GetKubeResp()), kubeResp)
+	if err != nil {
+		return false, errors.New("unmarshal KubeResp error: " + err.Error())
+	}
+	if kubeResp.Job != nil {
+		_, err = component.c.BatchV1().Jobs(kubeResp.Job.Namespace).Get(kubeResp.Job.Name)
+	} else if kubeResp.Pod != nil {
+		_, err = component.c.CoreV1().Pods(kubeResp.Pod.Namespace).Get(kubeResp.Pod.Name)
+	} else if kubeResp.Service != nil {
+		_, err = component.c.CoreV1().Services(kubeResp.Service.Namespace).Get(kubeResp.Service.Name)
+	} else {
+		return false, nil
+	}
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (component *kubeComponent) Stop() {
+	stopResource(component.SeqID, component)
+}
+
+func (component *kubeComponent) delete(context ExecutionContext) error {
+	//err := component.buildKubeClient(context.GetKubeMaster())
+	//if err != nil {
+	//	return errors.New("build kube client error: " + err.Error())
+	//}
+	kubeResp := new(types.KubeResp)
+	err := json.Unmarshal([]byte(context.GetKubeResp()), kubeResp)
+	if err != nil {
+		return errors.New("unmarshal KubeResp error: " + err.Error())
+	}
+	var errs error
+	if kubeResp.Job != nil {
+		orphanDependents := false
+		err = component.c.BatchV1().Jobs(kubeResp.Job.Namespace).Delete(kubeResp.Job.Name, &v1.DeleteOptions{OrphanDependents: &orphanDependents})
+		if err != nil {
+			errs = errors.New("delete job error: " + err.Error())
+		}
+	}
+	if kubeResp.Pod != nil {
+		err = component.c.CoreV1().Pods(kubeResp.Pod.Namespace).Delete(kubeResp.Pod.Name, &v1.DeleteOptions{})
+		if err != nil {
+			if errs != nil {
+				errs = errors.New(errs.Error() + ", delete pod error: " + err.Error())
+			} else {
+				errs = errors.New("delete pod error: " + err.Error())
+			}
+		}
+	}
+	if kubeResp.Service != nil {
+		err = component.c.CoreV1().Services(kubeResp.Service.Namespace).Delete(kubeResp.Service.Name, &v1.DeleteOptions{})
+		if err != nil {
+			if errs != nil {
+				errs = errors.New(errs.Error() + ", delete service error: " + err.Error())
+			} else {
+				errs = errors.New("delete service error: " + err.Error())
+			}
+		}
+	}
+	if kubeResp.Secret != nil {
+		err = component.c.CoreV1().Secrets(kubeResp.Secret.Namespace).Delete(kubeResp.Secret.Name, &v1.DeleteOptions{})
+		if err != nil {
+			if errs != nil {
+				errs = errors.New(errs.Error() + ", delete secret error: " + err.Error())
+			} else {
+				errs = errors.New("delete secret error: " + err.Error())
+			}
+		}
+	}
+	return errs
+}
+
+func (component *kubeComponent) GetExecutionContext() (ExecutionContext, error) {
+	return getExecutionContext(component.SeqID)
+}
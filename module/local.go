@@ -0,0 +1,313 @@
+package module
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/containerops/configure"
+	"github.com/sosozhuang/component/model"
+	"github.com/sosozhuang/component/types"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var localDockerHost string
+
+// processes holds the host processes started by local components, a process
+// can only be stopped by the daemon which started it.
+var processes = struct {
+	sync.Mutex
+	m map[int64]*exec.Cmd
+}{m: make(map[int64]*exec.Cmd)}
+
+func init() {
+	localDockerHost = configure.GetString("local.docker")
+	if localDockerHost == "" {
+		localDockerHost = "unix:///var/run/docker.sock"
+	}
+	RegisterBackend(model.ComponentTypeLocal, new(localBackend))
+}
+
+// processAllowed reports whether local components may run as host
+// processes, which execute the command of any component author on the
+// daemon host, so it is off unless local.allowprocess is set.
+func processAllowed() bool {
+	return configure.GetBool("local.allowprocess")
+}
+
+type localBackend struct{}
+
+func (backend *localBackend) Capabilities() Capability {
+	return CapabilityStop
+}
+
+// Prepare always returns the configured docker engine, so a local component
+// can be executed without any cluster. Any other master is rejected, a
+// caller could otherwise reach any docker engine the daemon host can.
+func (backend *localBackend) Prepare(executorName, master string) (string, error) {
+	if master != "" && master != localDockerHost {
+		return "", errors.New("local components run on the configured docker engine, should not specify master " + master)
+	}
+	return localDockerHost, nil
+}
+
+func (backend *localBackend) NewComponent(seqID int64, master string) (Component, error) {
+	client, err := newDockerClient(master)
+	if err != nil {
+		return nil, errors.New("build docker client error: " + err.Error())
+	}
+	return &localComponent{
+		SeqID: seqID,
+		c:     client,
+	}, nil
+}
+
+type dockerContainerConfig struct {
+	Image  string            `json:"Image"`
+	Env    []string          `json:"Env,omitempty"`
+	Labels map[string]string `json:"Labels,omitempty"`
+}
+
+type dockerContainerCreateResp struct {
+	ID       string   `json:"Id"`
+	Warnings []string `json:"Warnings"`
+}
+
+type dockerContainerResp struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Labels map[string]string `json:"Labels"`
+}
+
+func (backend *localBackend) collect(master string, executors []string) ([]GCResource, error) {
+	client, err := newDockerClient(master)
+	if err != nil {
+		return nil, errors.New("build docker client error: " + err.Error())
+	}
+	var containers []dockerContainerResp
+	query := url.Values{
+		"all":     []string{"1"},
+		"filters": []string{`{"label":["CO_EXECUTE_SEQ_ID","` + installationLabel + `=` + installationID() + `"]}`},
+	}
+	if err := client.do("GET", "/containers/json", query, nil, &containers); err != nil {
+		return nil, errors.New("list containers error: " + err.Error())
+	}
+	resources := make([]GCResource, 0)
+	for _, container := range containers {
+		seqID, ok := seqIDFromLabels(container.Labels)
+		if !ok {
+			continue
+		}
+		var name string
+		if len(container.Names) > 0 {
+			name = strings.TrimPrefix(container.Names[0], "/")
+		}
+		resources = append(resources, GCResource{Kind: "container", Name: name, ID: container.ID, ExecuteSeqID: seqID})
+	}
+	return resources, nil
+}
+
+func (backend *localBackend) remove(master string, r GCResource) error {
+	client, err := newDockerClient(master)
+	if err != nil {
+		return errors.New("build docker client error: " + err.Error())
+	}
+	query := url.Values{"force": []string{"true"}}
+	err = client.do("DELETE", "/containers/"+r.ID, query, nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+type localComponent struct {
+	SeqID int64
+	c     *dockerClient
+}
+
+func (component *localComponent) String() string {
+	return fmt.Sprintf("local component[%d]", component.SeqID)
+}
+
+func (component *localComponent) Start() {
+	startResource(component.SeqID, component)
+}
+
+func (component *localComponent) Stop() {
+	stopResource(component.SeqID, component)
+}
+
+func (component *localComponent) GetExecutionContext() (ExecutionContext, error) {
+	return getExecutionContext(component.SeqID)
+}
+
+func (component *localComponent) kind() string {
+	return "local"
+}
+
+func (component *localComponent) create(context ExecutionContext) (interface{}, error) {
+	localResp := new(types.LocalResp)
+	kubeSetting := new(types.KubeSetting)
+	err := json.Unmarshal([]byte(context.GetKubeSetting()), kubeSetting)
+	if err != nil {
+		return localResp, errors.New("unmarshal KubeSetting error: " + err.Error())
+	}
+	if kubeSetting.Process != nil {
+		localResp.Process, err = component.startProcess(context, kubeSetting.Process)
+		if err != nil {
+			return localResp, errors.New("start process error: " + err.Error())
+		}
+		return localResp, nil
+	}
+
+	localResp.Container, err = component.startContainer(context)
+	if err != nil {
+		return localResp, errors.New("start container error: " + err.Error())
+	}
+	return localResp, nil
+}
+
+func (component *localComponent) startProcess(context ExecutionContext, setting *types.ProcessSetting) (*types.LocalProcess, error) {
+	if !processAllowed() {
+		return nil, errors.New("local process components are disabled")
+	}
+	if len(setting.Command) == 0 {
+		return nil, errors.New("should specify process command")
+	}
+	cmd := exec.Command(setting.Command[0], setting.Command[1:]...)
+	cmd.Dir = setting.Dir
+	// The process must not inherit the environment of the daemon.
+	cmd.Env = make([]string, 0)
+	for _, env := range executionEnvs(context) {
+		cmd.Env = append(cmd.Env, env.Key+"="+env.Value)
+	}
+	if err := cmd.Start(); err != nil {
+		log.Errorf("Start local process%v error: %s", setting.Command, err)
+		return nil, err
+	}
+
+	seqID := context.GetExecuteSeqID()
+	processes.Lock()
+	processes.m[seqID] = cmd
+	processes.Unlock()
+	go func() {
+		err := cmd.Wait()
+		processes.Lock()
+		delete(processes.m, seqID)
+		processes.Unlock()
+		if err != nil {
+			log.Warnf("Local process of %s exited: %s\n", component, err)
+		} else {
+			log.Infof("Local process of %s exited\n", component)
+		}
+	}()
+	return &types.LocalProcess{Pid: cmd.Process.Pid}, nil
+}
+
+func (component *localComponent) startContainer(context ExecutionContext) (*types.LocalContainer, error) {
+	seqID := strconv.FormatInt(context.GetExecuteSeqID(), 10)
+	config := dockerContainerConfig{
+		Image: context.GetImageName(),
+		Labels: map[string]string{
+			"CO_EXECUTE_SEQ_ID": seqID,
+			"CO_EXECUTOR":       context.GetExecutorName(),
+			installationLabel:   installationID(),
+		},
+	}
+	if context.GetImageTag() != "" {
+		config.Image = config.Image + ":" + context.GetImageTag()
+	}
+	for _, env := range executionEnvs(context) {
+		config.Env = append(config.Env, env.Key+"="+env.Value)
+	}
+
+	name := "co-container-" + seqID
+	query := url.Values{"name": []string{name}}
+	var createResp dockerContainerCreateResp
+	err := component.c.do("POST", "/containers/create", query, config, &createResp)
+	if err != nil && strings.Contains(err.Error(), "No such image") {
+		pull := url.Values{"fromImage": []string{context.GetImageName()}}
+		if context.GetImageTag() != "" {
+			pull.Set("tag", context.GetImageTag())
+		}
+		if err := component.c.do("POST", "/images/create", pull, nil, nil); err != nil {
+			return nil, errors.New("pull image error: " + err.Error())
+		}
+		err = component.c.do("POST", "/containers/create", query, config, &createResp)
+	}
+	if err != nil {
+		log.Errorf("Create local container[%v] error: %s", config, err)
+		return nil, err
+	}
+
+	container := &types.LocalContainer{
+		ID:       createResp.ID,
+		Name:     name,
+		Warnings: createResp.Warnings,
+	}
+	err = component.c.do("POST", "/containers/"+createResp.ID+"/start", nil, nil, nil)
+	if err != nil {
+		return container, err
+	}
+	return container, nil
+}
+
+// exists reports a process alive when it was started by this daemon, and
+// unknown otherwise, as it may be run by another replica or outlive a
+// restarted daemon.
+func (component *localComponent) exists(context ExecutionContext) (bool, error) {
+	localResp := new(types.LocalResp)
+	err := json.Unmarshal([]byte(context.GetKubeResp()), localResp)
+	if err != nil {
+		return false, errors.New("unmarshal KubeResp error: " + err.Error())
+	}
+	if localResp.Process != nil {
+		processes.Lock()
+		_, ok := processes.m[context.GetExecuteSeqID()]
+		processes.Unlock()
+		if !ok {
+			return false, errExistenceUnknown
+		}
+		return true, nil
+	}
+	if localResp.Container == nil || localResp.Container.ID == "" {
+		return false, nil
+	}
+	err = component.c.do("GET", "/containers/"+localResp.Container.ID+"/json", nil, nil, nil)
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (component *localComponent) delete(context ExecutionContext) error {
+	localResp := new(types.LocalResp)
+	err := json.Unmarshal([]byte(context.GetKubeResp()), localResp)
+	if err != nil {
+		return errors.New("unmarshal KubeResp error: " + err.Error())
+	}
+	if localResp.Process != nil {
+		processes.Lock()
+		cmd, ok := processes.m[context.GetExecuteSeqID()]
+		processes.Unlock()
+		if !ok {
+			log.Warnf("Local process of %s already exited or not started by this daemon\n", component)
+			return nil
+		}
+		if err := cmd.Process.Kill(); err != nil {
+			return errors.New("kill process error: " + err.Error())
+		}
+	}
+	if localResp.Container != nil && localResp.Container.ID != "" {
+		query := url.Values{"force": []string{"true"}}
+		err = component.c.do("DELETE", "/containers/"+localResp.Container.ID, query, nil, nil)
+		if err != nil {
+			return errors.New("delete container error: " + err.Error())
+		}
+	}
+	return nil
+}
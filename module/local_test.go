@@ -0,0 +1,28 @@
+package module
+
+import (
+	"testing"
+)
+
+func TestLocalPrepare(t *testing.T) {
+	backend := new(localBackend)
+	tests := []struct {
+		master string
+		valid  bool
+	}{
+		{"", true},
+		{localDockerHost, true},
+		{"tcp://10.0.0.1:2375", false},
+		{"unix:///tmp/other.sock", false},
+	}
+	for _, test := range tests {
+		master, err := backend.Prepare("team", test.master)
+		if (err == nil) != test.valid {
+			t.Errorf("Prepare(%q) got error %v, want valid %v", test.master, err, test.valid)
+			continue
+		}
+		if err == nil && master != localDockerHost {
+			t.Errorf("Prepare(%q) got master %q, want %q", test.master, master, localDockerHost)
+		}
+	}
+}
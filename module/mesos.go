@@ -0,0 +1,335 @@
+package module
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/sosozhuang/component/model"
+	"github.com/sosozhuang/component/types"
+	"k8s.io/client-go/pkg/api/v1"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMarathonCpus = 0.1
+	defaultMarathonMem  = 128
+
+	marathonPollInterval = 5 * time.Second
+)
+
+// marathonClient sends requests to the marathon app rest api which
+// schedules mesos components.
+type marathonClient struct {
+	host string
+	c    *http.Client
+}
+
+func newMarathonClient(host string) (*marathonClient, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, errors.New("parse marathon host error: " + err.Error())
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("invalid marathon host url scheme: " + u.Scheme)
+	}
+	return &marathonClient{
+		host: strings.TrimSuffix(host, "/"),
+		c:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (client *marathonClient) do(method, path string, in, out interface{}) error {
+	return sendJSON(client.c, method, client.host+path, in, out)
+}
+
+type marathonApp struct {
+	ID        string             `json:"id"`
+	Instances int                `json:"instances"`
+	Cpus      float64            `json:"cpus"`
+	Mem       float64            `json:"mem"`
+	Container *marathonContainer `json:"container,omitempty"`
+	Env       map[string]string  `json:"env,omitempty"`
+	Labels    map[string]string  `json:"labels,omitempty"`
+}
+
+type marathonContainer struct {
+	Type   string          `json:"type"`
+	Docker *marathonDocker `json:"docker,omitempty"`
+}
+
+type marathonDocker struct {
+	Image          string `json:"image"`
+	Network        string `json:"network,omitempty"`
+	ForcePullImage bool   `json:"forcePullImage"`
+}
+
+// marathonTaskFailure is the last task of an app which ended, marathon
+// reports it whatever the task state.
+type marathonTaskFailure struct {
+	TaskID  string `json:"taskId"`
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+type marathonAppResp struct {
+	ID          string `json:"id"`
+	Version     string `json:"version"`
+	Deployments []struct {
+		ID string `json:"id"`
+	} `json:"deployments"`
+}
+
+func init() {
+	RegisterBackend(model.ComponentTypeMesos, new(mesosBackend))
+}
+
+type mesosBackend struct{}
+
+func (backend *mesosBackend) Capabilities() Capability {
+	return CapabilityStop
+}
+
+func (backend *mesosBackend) Prepare(executorName, master string) (string, error) {
+	if err := validateMaster(master); err != nil {
+		return "", err
+	}
+	return master, nil
+}
+
+func (backend *mesosBackend) NewComponent(seqID int64, master string) (Component, error) {
+	client, err := newMarathonClient(master)
+	if err != nil {
+		return nil, errors.New("build marathon client error: " + err.Error())
+	}
+	return &mesosComponent{
+		SeqID: seqID,
+		c:     client,
+	}, nil
+}
+
+func (backend *mesosBackend) collect(master string, executors []string) ([]GCResource, error) {
+	client, err := newMarathonClient(master)
+	if err != nil {
+		return nil, errors.New("build marathon client error: " + err.Error())
+	}
+	var appsResp struct {
+		Apps []struct {
+			ID     string            `json:"id"`
+			Labels map[string]string `json:"labels"`
+		} `json:"apps"`
+	}
+	selector := url.QueryEscape("CO_EXECUTE_SEQ_ID," + installationLabel + "==" + installationID())
+	if err := client.do("GET", "/v2/apps?label="+selector, nil, &appsResp); err != nil {
+		return nil, errors.New("list apps error: " + err.Error())
+	}
+	resources := make([]GCResource, 0)
+	for _, app := range appsResp.Apps {
+		if seqID, ok := seqIDFromLabels(app.Labels); ok {
+			resources = append(resources, GCResource{Kind: "app", Name: app.ID, ID: app.ID, ExecuteSeqID: seqID})
+		}
+	}
+	return resources, nil
+}
+
+func (backend *mesosBackend) remove(master string, r GCResource) error {
+	client, err := newMarathonClient(master)
+	if err != nil {
+		return errors.New("build marathon client error: " + err.Error())
+	}
+	err = client.do("DELETE", "/v2/apps"+r.ID+"?force=true", nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+type mesosComponent struct {
+	SeqID int64
+	c     *marathonClient
+}
+
+func (component *mesosComponent) String() string {
+	return fmt.Sprintf("mesos component[%d]", component.SeqID)
+}
+
+func (component *mesosComponent) Start() {
+	startResource(component.SeqID, component)
+}
+
+func (component *mesosComponent) Stop() {
+	stopResource(component.SeqID, component)
+}
+
+func (component *mesosComponent) GetExecutionContext() (ExecutionContext, error) {
+	return getExecutionContext(component.SeqID)
+}
+
+func (component *mesosComponent) kind() string {
+	return "mesos"
+}
+
+func (component *mesosComponent) create(context ExecutionContext) (interface{}, error) {
+	mesosResp := new(types.MesosResp)
+	seqID := strconv.FormatInt(context.GetExecuteSeqID(), 10)
+	image := context.GetImageName()
+	if context.GetImageTag() != "" {
+		image = image + ":" + context.GetImageTag()
+	}
+	app := marathonApp{
+		ID:        "/" + context.GetExecutorName() + "/co-app-" + seqID,
+		Instances: 1,
+		Cpus:      defaultMarathonCpus,
+		Mem:       defaultMarathonMem,
+		Container: &marathonContainer{
+			Type: "DOCKER",
+			Docker: &marathonDocker{
+				Image:   image,
+				Network: "BRIDGE",
+			},
+		},
+		Env: make(map[string]string),
+		Labels: map[string]string{
+			"CO_EXECUTE_SEQ_ID": seqID,
+			installationLabel:   installationID(),
+		},
+	}
+
+	// The resources of the first pod container, if any, are reused as the
+	// resources of the marathon app.
+	kubeSetting := new(types.KubeSetting)
+	if err := json.Unmarshal([]byte(context.GetKubeSetting()), kubeSetting); err != nil {
+		log.Warnln("Create marathon app unmarshal KubeSetting error:", err)
+	} else if kubeSetting.Pod != nil && len(kubeSetting.Pod.Spec.Containers) > 0 {
+		limits := kubeSetting.Pod.Spec.Containers[0].Resources.Limits
+		if cpu, ok := limits[v1.ResourceCPU]; ok {
+			app.Cpus = float64(cpu.MilliValue()) / 1000
+		}
+		if mem, ok := limits[v1.ResourceMemory]; ok {
+			app.Mem = float64(mem.Value()) / (1024 * 1024)
+		}
+	}
+	for _, env := range executionEnvs(context) {
+		app.Env[env.Key] = env.Value
+	}
+
+	var appResp marathonAppResp
+	err := component.c.do("POST", "/v2/apps", app, &appResp)
+	if err != nil {
+		log.Errorf("Create marathon app[%v] error: %s", app, err)
+		return mesosResp, errors.New("start app error: " + err.Error())
+	}
+	mesosResp.App = &types.MarathonApp{
+		ID:      appResp.ID,
+		Version: appResp.Version,
+	}
+	for _, deployment := range appResp.Deployments {
+		mesosResp.App.Deployments = append(mesosResp.App.Deployments, deployment.ID)
+	}
+	return mesosResp, nil
+}
+
+// watch runs the app of an execution once. A marathon app is a long running
+// service, marathon relaunches its task whenever it ends, so the app is
+// deleted as soon as its task ended or the execution terminated.
+func (component *mesosComponent) watch(context ExecutionContext) {
+	mesosResp := new(types.MesosResp)
+	if err := json.Unmarshal([]byte(context.GetKubeResp()), mesosResp); err != nil {
+		log.Errorln("Watch component unmarshal KubeResp error:", err)
+		return
+	}
+	if mesosResp.App == nil || mesosResp.App.ID == "" {
+		return
+	}
+	ticker := time.NewTicker(marathonPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		current, err := getExecutionContext(component.SeqID)
+		if err != nil {
+			log.Errorf("Get execution context of %s error: %s\n", component, err)
+			return
+		}
+		if IsTerminalStatus(current.GetStatus()) {
+			component.reclaim(current)
+			return
+		}
+
+		var appResp struct {
+			App struct {
+				LastTaskFailure *marathonTaskFailure `json:"lastTaskFailure"`
+			} `json:"app"`
+		}
+		err = component.c.do("GET", "/v2/apps"+mesosResp.App.ID+"?embed=app.lastTaskFailure", nil, &appResp)
+		if isNotFound(err) {
+			return
+		}
+		if err != nil {
+			log.Errorf("Watch app of %s error: %s\n", component, err)
+			continue
+		}
+		failure := appResp.App.LastTaskFailure
+		if failure == nil {
+			continue
+		}
+		reason := fmt.Sprintf("marathon task %s ended: %s %s", failure.TaskID, failure.State, failure.Message)
+		status := types.ComponentExecutionStatusFailed
+		if failure.State == "TASK_FINISHED" {
+			status = types.ComponentExecutionStatusFinished
+		}
+		// The execution may have terminated meanwhile, its app is deleted
+		// anyway.
+		if _, err := transitExecution(component.SeqID, status, reason); err != nil {
+			log.Warnf("Watch %s error: %s\n", component, err)
+		}
+		component.reclaim(current)
+		return
+	}
+}
+
+// reclaim deletes the app of a terminated execution before marathon
+// relaunches its task.
+func (component *mesosComponent) reclaim(context ExecutionContext) {
+	if err := component.delete(context); err != nil {
+		log.Errorf("Delete marathon app of %s error: %s\n", component, err)
+		return
+	}
+	if err := model.MarkExecutionReclaimed(component.SeqID); err != nil {
+		log.Errorf("Mark %s reclaimed error: %s\n", component, err)
+	}
+}
+
+func (component *mesosComponent) exists(context ExecutionContext) (bool, error) {
+	mesosResp := new(types.MesosResp)
+	err := json.Unmarshal([]byte(context.GetKubeResp()), mesosResp)
+	if err != nil {
+		return false, errors.New("unmarshal KubeResp error: " + err.Error())
+	}
+	if mesosResp.App == nil || mesosResp.App.ID == "" {
+		return false, nil
+	}
+	err = component.c.do("GET", "/v2/apps"+mesosResp.App.ID, nil, nil)
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (component *mesosComponent) delete(context ExecutionContext) error {
+	mesosResp := new(types.MesosResp)
+	err := json.Unmarshal([]byte(context.GetKubeResp()), mesosResp)
+	if err != nil {
+		return errors.New("unmarshal KubeResp error: " + err.Error())
+	}
+	if mesosResp.App == nil || mesosResp.App.ID == "" {
+		return nil
+	}
+	err = component.c.do("DELETE", "/v2/apps"+mesosResp.App.ID+"?force=true", nil, nil)
+	if err != nil && !isNotFound(err) {
+		return errors.New("delete app error: " + err.Error())
+	}
+	return nil
+}
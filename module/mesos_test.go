@@ -0,0 +1,181 @@
+package module
+
+import (
+	"encoding/json"
+	"github.com/sosozhuang/component/model"
+	"github.com/sosozhuang/component/types"
+	"net/http"
+	"testing"
+)
+
+func newMesosTestComponent(t *testing.T, host string, seqID int64) *mesosComponent {
+	client, err := newMarathonClient(host)
+	if err != nil {
+		t.Fatalf("newMarathonClient error: %s", err)
+	}
+	return &mesosComponent{SeqID: seqID, c: client}
+}
+
+func TestMesosCreate(t *testing.T) {
+	var app marathonApp
+	server, requests := fakeAPIServer(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&app); err != nil {
+			t.Errorf("decode app error: %s", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"/team/co-app-7","version":"v1","deployments":[{"id":"d1"}]}`))
+	})
+	defer server.Close()
+
+	component := newMesosTestComponent(t, server.URL, 7)
+	context := &componentExecutionContext{&model.ComponentExecution{
+		ID:        7,
+		Executor:  model.Executor{Name: "team"},
+		ImageName: "busybox",
+		ImageTag:  "1.0",
+		Input:     `{"a":1}`,
+		Envs:      `[{"key":"FOO","value":"bar"}]`,
+		KubeSetting: `{"pod":{"spec":{"containers":[{"name":"main",` +
+			`"resources":{"limits":{"cpu":"500m","memory":"256Mi"}}}]}}}`,
+	}}
+	resp, err := component.create(context)
+	if err != nil {
+		t.Fatalf("create error: %s", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(*requests))
+	}
+	r := (*requests)[0]
+	if r.Method != "POST" || r.URL.Path != "/v2/apps" {
+		t.Errorf("got %s %s, want POST /v2/apps", r.Method, r.URL.Path)
+	}
+	if app.ID != "/team/co-app-7" {
+		t.Errorf("got app id %q, want /team/co-app-7", app.ID)
+	}
+	if app.Container == nil || app.Container.Docker == nil || app.Container.Docker.Image != "busybox:1.0" {
+		t.Errorf("got container %+v, want docker image busybox:1.0", app.Container)
+	}
+	if app.Cpus != 0.5 {
+		t.Errorf("got cpus %v, want 0.5", app.Cpus)
+	}
+	if app.Mem != 256 {
+		t.Errorf("got mem %v, want 256", app.Mem)
+	}
+	wantEnv := map[string]string{
+		"FOO":               "bar",
+		"CO_EXECUTE_SEQ_ID": "7",
+		"CO_INPUT":          `{"a":1}`,
+	}
+	for key, want := range wantEnv {
+		if got := app.Env[key]; got != want {
+			t.Errorf("got env %s=%q, want %q", key, got, want)
+		}
+	}
+	if got := app.Labels["CO_EXECUTE_SEQ_ID"]; got != "7" {
+		t.Errorf("got label CO_EXECUTE_SEQ_ID=%q, want 7", got)
+	}
+	if got := app.Labels[installationLabel]; got != installationID() {
+		t.Errorf("got label %s=%q, want %q", installationLabel, got, installationID())
+	}
+
+	mesosResp, ok := resp.(*types.MesosResp)
+	if !ok || mesosResp.App == nil {
+		t.Fatalf("got resp %#v, want a marathon app", resp)
+	}
+	if mesosResp.App.ID != "/team/co-app-7" || mesosResp.App.Version != "v1" {
+		t.Errorf("got app %+v, want id /team/co-app-7 and version v1", *mesosResp.App)
+	}
+	if len(mesosResp.App.Deployments) != 1 || mesosResp.App.Deployments[0] != "d1" {
+		t.Errorf("got deployments %v, want [d1]", mesosResp.App.Deployments)
+	}
+}
+
+func TestMesosCreateDefaultResources(t *testing.T) {
+	var app marathonApp
+	server, _ := fakeAPIServer(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&app)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"/team/co-app-7"}`))
+	})
+	defer server.Close()
+
+	component := newMesosTestComponent(t, server.URL, 7)
+	context := &componentExecutionContext{&model.ComponentExecution{
+		ID:          7,
+		Executor:    model.Executor{Name: "team"},
+		ImageName:   "busybox",
+		KubeSetting: `{}`,
+	}}
+	if _, err := component.create(context); err != nil {
+		t.Fatalf("create error: %s", err)
+	}
+	if app.Cpus != defaultMarathonCpus || app.Mem != defaultMarathonMem {
+		t.Errorf("got cpus %v and mem %v, want %v and %v", app.Cpus, app.Mem, defaultMarathonCpus, defaultMarathonMem)
+	}
+	if app.Container == nil || app.Container.Docker == nil || app.Container.Docker.Image != "busybox" {
+		t.Errorf("got container %+v, want docker image busybox", app.Container)
+	}
+}
+
+func TestMesosDelete(t *testing.T) {
+	server, requests := fakeAPIServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"deploymentId":"d2","version":"v2"}`))
+	})
+	defer server.Close()
+
+	component := newMesosTestComponent(t, server.URL, 7)
+	context := &componentExecutionContext{&model.ComponentExecution{
+		ID:       7,
+		KubeResp: `{"app":{"id":"/team/co-app-7","version":"v1"}}`,
+	}}
+	if err := component.delete(context); err != nil {
+		t.Fatalf("delete error: %s", err)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(*requests))
+	}
+	r := (*requests)[0]
+	if r.Method != "DELETE" || r.URL.Path != "/v2/apps/team/co-app-7" {
+		t.Errorf("got %s %s, want DELETE /v2/apps/team/co-app-7", r.Method, r.URL.Path)
+	}
+	if force := r.URL.Query().Get("force"); force != "true" {
+		t.Errorf("got force=%q, want true", force)
+	}
+}
+
+func TestMesosDeleteError(t *testing.T) {
+	server, _ := fakeAPIServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"message":"app is locked"}`))
+	})
+	defer server.Close()
+
+	component := newMesosTestComponent(t, server.URL, 7)
+	context := &componentExecutionContext{&model.ComponentExecution{
+		ID:       7,
+		KubeResp: `{"app":{"id":"/team/co-app-7"}}`,
+	}}
+	if err := component.delete(context); err == nil {
+		t.Error("delete succeeded, want an error")
+	}
+}
+
+func TestMesosDeleteNotFound(t *testing.T) {
+	server, _ := fakeAPIServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"app not found"}`))
+	})
+	defer server.Close()
+
+	component := newMesosTestComponent(t, server.URL, 7)
+	context := &componentExecutionContext{&model.ComponentExecution{
+		ID:       7,
+		KubeResp: `{"app":{"id":"/team/co-app-7"}}`,
+	}}
+	if err := component.delete(context); err != nil {
+		t.Errorf("delete error: %s, want none for a deleted app", err)
+	}
+}
@@ -0,0 +1,310 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/containerops/configure"
+	"github.com/jinzhu/gorm"
+	"github.com/sosozhuang/component/model"
+	"github.com/sosozhuang/component/types"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	NotificationTypeStatusChanged = "status_changed"
+
+	defaultOutboxInterval    = 5 * time.Second
+	defaultOutboxMaxAttempts = 10
+	outboxBatchSize          = 100
+	notificationBackoffBase  = 5 * time.Second
+	notificationBackoffMax   = 10 * time.Minute
+	// notificationLease is how long a claimed notification waits before it
+	// is attempted again, in case the daemon which claimed it exits. It is
+	// longer than the timeout of a request.
+	notificationLease = time.Minute
+	// outboxBatchTimeout bounds the time spent on the due notifications
+	// selected at one tick.
+	outboxBatchTimeout = 2 * time.Minute
+)
+
+var notificationClient = &http.Client{Timeout: 30 * time.Second}
+
+func outboxMaxAttempts() int {
+	value := configure.GetString("outbox.maxattempts")
+	if value == "" {
+		return defaultOutboxMaxAttempts
+	}
+	maxAttempts, err := strconv.Atoi(value)
+	if err != nil || maxAttempts <= 0 {
+		log.Warnf("Invalid outbox.maxattempts %q, use %d\n", value, defaultOutboxMaxAttempts)
+		return defaultOutboxMaxAttempts
+	}
+	return maxAttempts
+}
+
+// notificationBackoff returns the delay before the next attempt of a
+// notification which failed attempts times.
+func notificationBackoff(attempts int) time.Duration {
+	backoff := notificationBackoffBase
+	for i := 1; i < attempts && backoff < notificationBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > notificationBackoffMax {
+		backoff = notificationBackoffMax
+	}
+	return backoff
+}
+
+// executionTx is the transaction holding an execution selected for update.
+type executionTx interface {
+	CreateNotification(n *model.Notification) error
+	Save() error
+	Rollback()
+}
+
+// saveExecution saves an execution along with the webhook of messageType in
+// one transaction, so no webhook is lost if the daemon exits once the status
+// is committed. The webhook is then attempted at once and the message
+// published to live subscribers.
+func saveExecution(tx executionTx, context ExecutionContext, messageType string, events []types.EventMsg) error {
+	message := executionMessage(context, messageType, events)
+	notification, err := newNotification(message)
+	if err != nil {
+		log.Errorf("Enqueue %s notification of execution %d error: %s\n", messageType, message.ExecuteSeqID, err)
+	} else if notification != nil {
+		if err := tx.CreateNotification(notification); err != nil {
+			tx.Rollback()
+			return errors.New("create notification error: " + err.Error())
+		}
+	}
+	if err := tx.Save(); err != nil {
+		return err
+	}
+	if notification != nil {
+		go attemptNotification(notification.ID, true)
+	}
+	publishMessage(message)
+	return nil
+}
+
+// newNotification returns the webhook request of message, or nil if the
+// execution has no notify url of the message type.
+func newNotification(message Message) (*model.Notification, error) {
+	var url string
+	notifyUrl := message.NotifyUrl
+	switch message.Type {
+	case NotificationTypeStatusChanged:
+		url = notifyUrl.StatusChanged
+	case string(model.EventTypeComponentStart):
+		url = notifyUrl.ComponentStart
+	case string(model.EventTypeComponentResult):
+		url = notifyUrl.ComponentResult
+	case string(model.EventTypeComponentStop):
+		url = notifyUrl.ComponentStop
+	}
+	if url == "" {
+		log.Debugf("Execute seq id %d, type %s, can't find notify url\n", message.ExecuteSeqID, message.Type)
+		return nil, nil
+	}
+	payload, err := json.Marshal(message.ExecuteComponentMsg)
+	if err != nil {
+		return nil, errors.New("marshal message error: " + err.Error())
+	}
+	now := time.Now()
+	return &model.Notification{
+		ExecuteSeqID:  message.ExecuteSeqID,
+		Type:          message.Type,
+		Url:           url,
+		Payload:       string(payload),
+		Status:        model.NotificationStatusPending,
+		NextAttemptAt: &now,
+	}, nil
+}
+
+// attemptNotification delivers notification id if no other daemon claimed
+// it. A notification just saved is attempted at once, otherwise only when
+// its next attempt is due.
+func attemptNotification(id int64, immediate bool) {
+	notification, err := model.SelectNotification(id)
+	if err != nil {
+		log.Errorf("Select notification %d error: %s\n", id, err)
+		return
+	}
+	if notification.Status != model.NotificationStatusPending || notification.NextAttemptAt == nil {
+		return
+	}
+	if !immediate && notification.NextAttemptAt.After(time.Now()) {
+		return
+	}
+	// The claim compares the next attempt read above, a database which
+	// rounds the time still matches it.
+	claimed, err := model.ClaimNotification(id, *notification.NextAttemptAt, time.Now().Add(notificationLease))
+	if err != nil {
+		log.Errorf("Claim notification %d error: %s\n", id, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	err = postNotification(notification)
+	attempts := notification.Attempts + 1
+	now := time.Now()
+	columns := map[string]interface{}{"attempts": attempts}
+	if err == nil {
+		columns["status"] = model.NotificationStatusDelivered
+		columns["delivered_at"] = now
+		columns["next_attempt_at"] = nil
+		columns["last_error"] = ""
+	} else {
+		log.Errorf("Deliver %s notification of execution %d to %s error: %s\n",
+			notification.Type, notification.ExecuteSeqID, notification.Url, err)
+		columns["last_error"] = err.Error()
+		if attempts >= outboxMaxAttempts() {
+			columns["status"] = model.NotificationStatusDead
+			columns["next_attempt_at"] = nil
+		} else {
+			columns["next_attempt_at"] = now.Add(notificationBackoff(attempts))
+		}
+	}
+	if _, err := model.UpdateNotification(id, model.NotificationStatusPending, columns); err != nil {
+		log.Errorf("Save notification %d error: %s\n", id, err)
+	}
+}
+
+// executorKey returns the key of the executor of execution seqID, the key
+// is read at every attempt so a rotated key takes effect at once.
+func executorKey(seqID int64) (string, error) {
+	execution, err := model.SelectComponentLogFromID(seqID)
+	if err != nil {
+		return "", errors.New("get component execution error: " + err.Error())
+	}
+	executor, err := model.SelectExecutorFromID(execution.ExecutorID)
+	if err != nil {
+		return "", errors.New("get executor error: " + err.Error())
+	}
+	return executor.Key, nil
+}
+
+// postNotification sends a notification signed by the executor key.
+func postNotification(notification *model.Notification) error {
+	body := []byte(notification.Payload)
+	req, err := http.NewRequest("POST", notification.Url, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("create request error: " + err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	key, err := executorKey(notification.ExecuteSeqID)
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		if err := signRequest(req, key, body); err != nil {
+			return err
+		}
+	}
+	resp, err := notificationClient.Do(req)
+	if err != nil {
+		return errors.New("send request error: " + err.Error())
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("response code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// StartOutbox attempts the due notifications on the configured interval,
+// it never returns.
+func StartOutbox() {
+	ticker := time.NewTicker(configDuration("outbox.interval", defaultOutboxInterval))
+	defer ticker.Stop()
+	for range ticker.C {
+		ids, err := model.SelectDueNotificationIDs(time.Now(), outboxBatchSize)
+		if err != nil {
+			log.Errorln("Outbox select due notifications error:", err)
+			continue
+		}
+		// The rest of a slow batch is left to the next tick.
+		deadline := time.Now().Add(outboxBatchTimeout)
+		for _, id := range ids {
+			if time.Now().After(deadline) {
+				break
+			}
+			attemptNotification(id, false)
+		}
+	}
+}
+
+// GetNotifications returns the notifications of execution seqID, all of
+// them if status is empty.
+func GetNotifications(seqID int64, status string) ([]model.Notification, error) {
+	switch status {
+	case "", model.NotificationStatusPending, model.NotificationStatusDelivered, model.NotificationStatusDead:
+	default:
+		return nil, fmt.Errorf("invalid notification status: %s", status)
+	}
+	notifications, err := model.SelectNotifications(seqID, status)
+	if err != nil {
+		return nil, errors.New("get notifications error: " + err.Error())
+	}
+	return notifications, nil
+}
+
+// ReplayNotifications attempts the dead notifications of execution seqID
+// again with a fresh attempt budget, or only notification id if it is not
+// zero, which must be dead too. A pending notification may be in flight.
+func ReplayNotifications(seqID, id int64) ([]model.Notification, error) {
+	var notifications []model.Notification
+	if id != 0 {
+		notification, err := model.SelectNotification(id)
+		if err == gorm.ErrRecordNotFound || (err == nil && notification.ExecuteSeqID != seqID) {
+			return nil, errors.New("notification not found")
+		}
+		if err != nil {
+			return nil, errors.New("get notification error: " + err.Error())
+		}
+		if notification.Status != model.NotificationStatusDead {
+			return nil, fmt.Errorf("notification is %s, only dead notifications can be replayed", notification.Status)
+		}
+		notifications = append(notifications, *notification)
+	} else {
+		var err error
+		notifications, err = model.SelectNotifications(seqID, model.NotificationStatusDead)
+		if err != nil {
+			return nil, errors.New("get notifications error: " + err.Error())
+		}
+	}
+
+	now := time.Now()
+	replayed := make([]model.Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		// Only the update which finds the notification still dead replays
+		// it, a concurrent replay leaves it alone.
+		ok, err := model.UpdateNotification(notification.ID, model.NotificationStatusDead, map[string]interface{}{
+			"status":          model.NotificationStatusPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+		})
+		if err != nil {
+			return nil, errors.New("save notification error: " + err.Error())
+		}
+		if !ok {
+			continue
+		}
+		notification.Status = model.NotificationStatusPending
+		notification.Attempts = 0
+		notification.NextAttemptAt = &now
+		replayed = append(replayed, notification)
+		go attemptNotification(notification.ID, true)
+	}
+	return replayed, nil
+}
@@ -0,0 +1,208 @@
+package module
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/sosozhuang/component/model"
+	"github.com/sosozhuang/component/types"
+	"time"
+)
+
+const (
+	defaultReconcileInterval = time.Minute
+	// defaultReconcileGrace is how long an execution is left alone after its
+	// last update, so resources being created or executions waiting for the
+	// component_stop event are not mistaken for leftovers.
+	defaultReconcileGrace = 5 * time.Minute
+	// defaultWatchLease is how long the watcher of a daemon which exited
+	// without releasing its lease blocks other replicas from resuming it.
+	defaultWatchLease = time.Minute
+)
+
+// StartReconciler compares the executions in the database with their live
+// resources once at start and then on every interval, it never returns.
+func StartReconciler() {
+	interval := configDuration("reconciler.interval", defaultReconcileInterval)
+	grace := configDuration("reconciler.grace", defaultReconcileGrace)
+
+	reconcile(true, grace)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		reconcile(false, grace)
+	}
+}
+
+// reconcile fails the running executions whose resources vanished and
+// deletes the resources of terminated executions. The watchers of running
+// executions are resumed if resume is true, as they are lost when the daemon
+// restarts.
+func reconcile(resume bool, grace time.Duration) {
+	ids, err := model.SelectRunningExecutionIDs()
+	if err != nil {
+		log.Errorln("Reconcile select running executions error:", err)
+	}
+	for _, id := range ids {
+		reconcileRunning(id, resume, grace)
+	}
+
+	ids, err = model.SelectUnreclaimedExecutionIDs(time.Now().Add(-grace))
+	if err != nil {
+		log.Errorln("Reconcile select unreclaimed executions error:", err)
+	}
+	for _, id := range ids {
+		reconcileTerminated(id)
+	}
+}
+
+// reconcileComponent returns the component of an execution, it returns nil
+// if the backend of the execution can't inspect its resources.
+func reconcileComponent(componentExecution *model.ComponentExecution) Component {
+	backend, err := getBackend(componentExecution.Type)
+	if err != nil {
+		log.Errorf("Reconcile execution %d error: %s\n", componentExecution.ID, err)
+		return nil
+	}
+	c, err := backend.NewComponent(componentExecution.ID, componentExecution.KubeMaster)
+	if err != nil {
+		log.Errorf("Reconcile execution %d error: %s\n", componentExecution.ID, err)
+		return nil
+	}
+	if _, ok := c.(inspector); !ok {
+		return nil
+	}
+	if _, ok := c.(resource); !ok {
+		return nil
+	}
+	return c
+}
+
+// watchLeased runs w while this daemon holds the lease of execution seqID,
+// it returns at once if another replica holds it.
+func watchLeased(seqID int64, w watcher, context ExecutionContext) {
+	lease := configDuration("reconciler.lease", defaultWatchLease)
+	now := time.Now()
+	claimed, err := model.ClaimExecutionLease(seqID, replicaID, now, now.Add(lease))
+	if err != nil {
+		log.Errorf("Watch execution %d claim lease error: %s\n", seqID, err)
+		return
+	}
+	if !claimed {
+		log.Debugf("Watch execution %d skipped: another replica holds the lease\n", seqID)
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				renewed, err := model.RenewExecutionLease(seqID, replicaID, time.Now().Add(lease))
+				if err != nil {
+					log.Errorf("Watch execution %d renew lease error: %s\n", seqID, err)
+				} else if !renewed {
+					log.Warnf("Watch execution %d lost its lease\n", seqID)
+				}
+			}
+		}
+	}()
+	w.watch(context)
+	close(done)
+	if err := model.ReleaseExecutionLease(seqID, replicaID); err != nil {
+		log.Errorf("Watch execution %d release lease error: %s\n", seqID, err)
+	}
+}
+
+func reconcileRunning(seqID int64, resume bool, grace time.Duration) {
+	componentExecution, err := model.SelectComponentLogFromID(seqID)
+	if err != nil {
+		log.Errorf("Reconcile execution %d error: %s\n", seqID, err)
+		return
+	}
+	if IsTerminalStatus(componentExecution.Status) {
+		return
+	}
+	c := reconcileComponent(componentExecution)
+	if c == nil {
+		return
+	}
+	context := &componentExecutionContext{componentExecution}
+	alive, err := c.(inspector).exists(context)
+	if err == errExistenceUnknown {
+		log.Debugf("Reconcile execution %d skipped: %s\n", seqID, err)
+		return
+	}
+	if err != nil {
+		log.Errorf("Reconcile execution %d inspect resource error: %s\n", seqID, err)
+		return
+	}
+	if alive {
+		if w, ok := c.(watcher); ok && resume {
+			go watchLeased(seqID, w, context)
+		}
+		return
+	}
+	if time.Since(componentExecution.UpdatedAt) < grace {
+		return
+	}
+	_, err = transitExecution(seqID, types.ComponentExecutionStatusFailed,
+		"reconciler found "+c.(resource).kind()+" resource vanished")
+	if err != nil {
+		log.Warnf("Reconcile execution %d error: %s\n", seqID, err)
+		return
+	}
+	if err := model.MarkExecutionReclaimed(seqID); err != nil {
+		log.Errorf("Reconcile execution %d mark reclaimed error: %s\n", seqID, err)
+	}
+}
+
+func reconcileTerminated(seqID int64) {
+	componentExecution, err := model.SelectComponentLogFromID(seqID)
+	if err != nil {
+		log.Errorf("Reconcile execution %d error: %s\n", seqID, err)
+		return
+	}
+	c := reconcileComponent(componentExecution)
+	if c == nil {
+		return
+	}
+	r := c.(resource)
+	context := &componentExecutionContext{componentExecution}
+	alive, err := c.(inspector).exists(context)
+	if err == errExistenceUnknown {
+		log.Debugf("Reconcile execution %d skipped: %s\n", seqID, err)
+		return
+	}
+	if err != nil {
+		log.Errorf("Reconcile execution %d inspect resource error: %s\n", seqID, err)
+		return
+	}
+	if !alive {
+		if err := model.MarkExecutionReclaimed(seqID); err != nil {
+			log.Errorf("Reconcile execution %d mark reclaimed error: %s\n", seqID, err)
+		}
+		return
+	}
+
+	archiveLogs(r, context)
+	if err := r.delete(context); err != nil {
+		// The delete is retried on the next reconcile.
+		log.Errorf("Reconcile execution %d delete resource error: %s\n", seqID, err)
+		return
+	}
+	execution, err := model.SelectComponentExecutionForUpdate(seqID)
+	if err != nil {
+		log.Errorf("Reconcile execution %d error: %s\n", seqID, err)
+		return
+	}
+	execution.Reclaimed = true
+	execution.Detail = execution.Detail +
+		time.Now().Format("2006-01-02 15:04:05") +
+		" reconciler deleted leftover " + r.kind() + " resource.\n"
+	if err := execution.Save(); err != nil {
+		log.Errorf("Reconcile execution %d save error: %s\n", seqID, err)
+	}
+}
@@ -0,0 +1,92 @@
+package module
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/containerops/configure"
+	"github.com/sosozhuang/component/model"
+	"github.com/sosozhuang/component/types"
+	"time"
+)
+
+const (
+	defaultSchedulerInterval = 10 * time.Second
+	// timeoutRetryInterval is how long a claimed deadline waits before it is
+	// enforced again, in case the daemon which claimed it exits before the
+	// execution is stopped.
+	timeoutRetryInterval = time.Minute
+)
+
+// StartTimeoutScheduler enforces the deadlines of running executions, it
+// never returns. Deadlines are stored with the executions, so the ones which
+// expired while no daemon was running are enforced on the next start.
+func StartTimeoutScheduler() {
+	interval := configDuration("scheduler.interval", defaultSchedulerInterval)
+	if err := model.BackfillExecutionDeadlines(); err != nil {
+		log.Errorln("Timeout scheduler backfill execution deadlines error:", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		enforceDeadlines(time.Now())
+		<-ticker.C
+	}
+}
+
+// configDuration parses the duration configured by key.
+func configDuration(key string, defaultValue time.Duration) time.Duration {
+	value := configure.GetString(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Warnf("Invalid %s %q, use %s\n", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+func enforceDeadlines(now time.Time) {
+	ids, err := model.SelectExpiredExecutionIDs(now)
+	if err != nil {
+		log.Errorln("Timeout scheduler select expired executions error:", err)
+		return
+	}
+	for _, id := range ids {
+		claimed, err := model.ClaimExpiredExecution(id, now, now.Add(timeoutRetryInterval))
+		if err != nil {
+			log.Errorf("Timeout scheduler claim execution %d error: %s\n", id, err)
+			continue
+		}
+		if claimed {
+			go expireExecution(id)
+		}
+	}
+}
+
+func expireExecution(seqID int64) {
+	context, err := getExecutionContext(seqID)
+	if err != nil {
+		log.Errorf("Expire execution %d error: %s\n", seqID, err)
+		return
+	}
+	backend, err := getBackend(context.GetType())
+	if err != nil {
+		log.Errorf("Expire execution %d error: %s\n", seqID, err)
+		return
+	}
+	if backend.Capabilities()&CapabilityStop == 0 {
+		transitExecution(seqID, types.ComponentExecutionStatusFailed, "execution is timeout")
+		return
+	}
+	if err := appendExecutionDetail(seqID, "execution is timeout"); err != nil {
+		log.Errorf("Expire execution %d append detail error: %s\n", seqID, err)
+	}
+	c, err := backend.NewComponent(seqID, context.GetKubeMaster())
+	if err != nil {
+		log.Errorf("Expire execution %d error: %s\n", seqID, err)
+		return
+	}
+	c.Stop()
+}
@@ -0,0 +1,85 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xeipuuv/gojsonschema"
+	"strings"
+)
+
+// SchemaError is a place where a document doesn't match its json schema.
+type SchemaError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every mismatch of a component input or output
+// against the schema declared by the component.
+type ValidationError struct {
+	Document string
+	Errors   []SchemaError
+}
+
+func (e *ValidationError) Error() string {
+	details := make([]string, 0, len(e.Errors))
+	for _, schemaError := range e.Errors {
+		details = append(details, schemaError.Path+": "+schemaError.Message)
+	}
+	return fmt.Sprintf("%s doesn't match schema: %s", e.Document, strings.Join(details, "; "))
+}
+
+func loadSchema(document, schema string) (*gojsonschema.Schema, error) {
+	s, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		return nil, errors.New("load " + document + " schema error: " + err.Error())
+	}
+	return s, nil
+}
+
+// validateSchemas checks the schemas declared by component are valid json
+// schemas.
+func validateSchemas(inputSchema, outputSchema string) error {
+	if strings.TrimSpace(inputSchema) != "" {
+		if _, err := loadSchema("input", inputSchema); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(outputSchema) != "" {
+		if _, err := loadSchema("output", outputSchema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateDocument checks content against schema, an empty schema accepts
+// anything. The paths of a *ValidationError start with document, like
+// input.items.0.name.
+func validateDocument(document, schema string, content []byte) error {
+	if strings.TrimSpace(schema) == "" {
+		return nil
+	}
+	s, err := loadSchema(document, schema)
+	if err != nil {
+		return err
+	}
+	result, err := s.Validate(gojsonschema.NewBytesLoader(content))
+	if err != nil {
+		return errors.New("parse " + document + " error: " + err.Error())
+	}
+	if result.Valid() {
+		return nil
+	}
+	validationError := &ValidationError{Document: document}
+	for _, resultError := range result.Errors() {
+		path := document
+		if field := resultError.Field(); field != "" && field != gojsonschema.STRING_CONTEXT_ROOT {
+			path = path + "." + field
+		}
+		validationError.Errors = append(validationError.Errors, SchemaError{
+			Path:    path,
+			Message: resultError.Description(),
+		})
+	}
+	return validationError
+}
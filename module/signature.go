@@ -0,0 +1,126 @@
+package module
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/sosozhuang/component/model"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The headers which sign a webhook sent to an executor, or an event sent by
+// a component. The signature is the hex encoded HMAC-SHA256 of
+// timestamp + "\n" + nonce + "\n" + body, keyed by the executor key for
+// webhooks and by the CO_EVENT_TOKEN of the execution for events.
+const (
+	HeaderTimestamp = "X-Component-Timestamp"
+	HeaderNonce     = "X-Component-Nonce"
+	HeaderSignature = "X-Component-Signature"
+
+	// signatureMaxSkew is how far the timestamp of a signed request may be
+	// from now, a nonce is remembered for as long.
+	signatureMaxSkew = 5 * time.Minute
+)
+
+// maxNonceLength is the length of the longest nonce which can be recorded.
+const maxNonceLength = 64
+
+// noncesPruned is when the expired nonces were last deleted.
+var noncesPruned = struct {
+	sync.Mutex
+	t time.Time
+}{}
+
+// generateKey returns a random hex encoded key of 32 bytes.
+func generateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.New("generate key error: " + err.Error())
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.New("generate nonce error: " + err.Error())
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func sign(key, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp + "\n" + nonce + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signRequest sets the signature headers of req whose body is body.
+func signRequest(req *http.Request, key string, body []byte) error {
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, sign(key, timestamp, nonce, body))
+	return nil
+}
+
+// verifySignature checks signature was made by key for body, and rejects a
+// stale timestamp or a nonce used twice in scope.
+func verifySignature(key, scope, timestamp, nonce, signature string, body []byte) error {
+	if timestamp == "" || nonce == "" || signature == "" {
+		return errors.New("missing signature headers")
+	}
+	if len(nonce) > maxNonceLength {
+		return errors.New("signature nonce too long")
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid signature timestamp")
+	}
+	skew := time.Since(time.Unix(seconds, 0))
+	if skew > signatureMaxSkew || skew < -signatureMaxSkew {
+		return errors.New("signature timestamp expired")
+	}
+	if !hmac.Equal([]byte(sign(key, timestamp, nonce, body)), []byte(signature)) {
+		return errors.New("signature mismatch")
+	}
+
+	// Nonces are recorded in the database, so a request can't be replayed
+	// to another replica either.
+	now := time.Now()
+	used, err := model.UseNonce(scope, nonce, now, now.Add(2*signatureMaxSkew))
+	if err != nil {
+		return errors.New("record nonce error: " + err.Error())
+	}
+	if !used {
+		return fmt.Errorf("nonce %s already used", nonce)
+	}
+	pruneNonces(now)
+	return nil
+}
+
+// pruneNonces deletes the expired nonces at most once per skew window.
+func pruneNonces(now time.Time) {
+	noncesPruned.Lock()
+	defer noncesPruned.Unlock()
+	if now.Sub(noncesPruned.t) < signatureMaxSkew {
+		return
+	}
+	noncesPruned.t = now
+	go func() {
+		if err := model.DeleteExpiredNonces(now); err != nil {
+			log.Errorln("Delete expired nonces error:", err)
+		}
+	}()
+}
@@ -0,0 +1,216 @@
+package module
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/sosozhuang/component/model"
+	"github.com/sosozhuang/component/types"
+	"net/url"
+	"strconv"
+)
+
+type swarmServiceSpec struct {
+	Name         string            `json:"Name"`
+	Labels       map[string]string `json:"Labels,omitempty"`
+	TaskTemplate swarmTaskSpec     `json:"TaskTemplate"`
+	Mode         swarmServiceMode  `json:"Mode"`
+}
+
+type swarmTaskSpec struct {
+	ContainerSpec swarmContainerSpec  `json:"ContainerSpec"`
+	RestartPolicy *swarmRestartPolicy `json:"RestartPolicy,omitempty"`
+}
+
+type swarmContainerSpec struct {
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels,omitempty"`
+	Env    []string          `json:"Env,omitempty"`
+}
+
+type swarmRestartPolicy struct {
+	Condition string `json:"Condition"`
+}
+
+type swarmServiceMode struct {
+	Replicated *swarmReplicatedService `json:"Replicated,omitempty"`
+}
+
+type swarmReplicatedService struct {
+	Replicas *uint64 `json:"Replicas,omitempty"`
+}
+
+type swarmServiceCreateResp struct {
+	ID       string   `json:"ID"`
+	Warnings []string `json:"Warnings"`
+}
+
+func init() {
+	RegisterBackend(model.ComponentTypeSwarm, new(swarmBackend))
+}
+
+type swarmBackend struct{}
+
+func (backend *swarmBackend) Capabilities() Capability {
+	return CapabilityStop
+}
+
+func (backend *swarmBackend) Prepare(executorName, master string) (string, error) {
+	if err := validateMaster(master); err != nil {
+		return "", err
+	}
+	return master, nil
+}
+
+func (backend *swarmBackend) NewComponent(seqID int64, master string) (Component, error) {
+	client, err := newDockerClient(master)
+	if err != nil {
+		return nil, errors.New("build docker client error: " + err.Error())
+	}
+	return &swarmComponent{
+		SeqID: seqID,
+		c:     client,
+	}, nil
+}
+
+type swarmServiceResp struct {
+	ID   string `json:"ID"`
+	Spec struct {
+		Name   string            `json:"Name"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Spec"`
+}
+
+func (backend *swarmBackend) collect(master string, executors []string) ([]GCResource, error) {
+	client, err := newDockerClient(master)
+	if err != nil {
+		return nil, errors.New("build docker client error: " + err.Error())
+	}
+	var services []swarmServiceResp
+	query := url.Values{"filters": []string{`{"label":["CO_EXECUTE_SEQ_ID","` + installationLabel + `=` + installationID() + `"]}`}}
+	if err := client.do("GET", "/services", query, nil, &services); err != nil {
+		return nil, errors.New("list services error: " + err.Error())
+	}
+	resources := make([]GCResource, 0)
+	for _, service := range services {
+		if seqID, ok := seqIDFromLabels(service.Spec.Labels); ok {
+			resources = append(resources, GCResource{Kind: "service", Name: service.Spec.Name, ID: service.ID, ExecuteSeqID: seqID})
+		}
+	}
+	return resources, nil
+}
+
+func (backend *swarmBackend) remove(master string, r GCResource) error {
+	client, err := newDockerClient(master)
+	if err != nil {
+		return errors.New("build docker client error: " + err.Error())
+	}
+	err = client.do("DELETE", "/services/"+r.ID, nil, nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+type swarmComponent struct {
+	SeqID int64
+	c     *dockerClient
+}
+
+func (component *swarmComponent) String() string {
+	return fmt.Sprintf("swarm component[%d]", component.SeqID)
+}
+
+func (component *swarmComponent) Start() {
+	startResource(component.SeqID, component)
+}
+
+func (component *swarmComponent) Stop() {
+	stopResource(component.SeqID, component)
+}
+
+func (component *swarmComponent) GetExecutionContext() (ExecutionContext, error) {
+	return getExecutionContext(component.SeqID)
+}
+
+func (component *swarmComponent) kind() string {
+	return "swarm"
+}
+
+func (component *swarmComponent) create(context ExecutionContext) (interface{}, error) {
+	swarmResp := new(types.SwarmResp)
+	seqID := strconv.FormatInt(context.GetExecuteSeqID(), 10)
+	labels := map[string]string{
+		"CO_EXECUTE_SEQ_ID": seqID,
+		"CO_EXECUTOR":       context.GetExecutorName(),
+		installationLabel:   installationID(),
+	}
+	image := context.GetImageName()
+	if context.GetImageTag() != "" {
+		image = image + ":" + context.GetImageTag()
+	}
+	var replicas uint64 = 1
+	spec := swarmServiceSpec{
+		Name:   "co-svc-" + seqID,
+		Labels: labels,
+		TaskTemplate: swarmTaskSpec{
+			ContainerSpec: swarmContainerSpec{
+				Image:  image,
+				Labels: labels,
+			},
+			RestartPolicy: &swarmRestartPolicy{Condition: "on-failure"},
+		},
+		Mode: swarmServiceMode{
+			Replicated: &swarmReplicatedService{Replicas: &replicas},
+		},
+	}
+	for _, env := range executionEnvs(context) {
+		spec.TaskTemplate.ContainerSpec.Env = append(spec.TaskTemplate.ContainerSpec.Env, env.Key+"="+env.Value)
+	}
+
+	var createResp swarmServiceCreateResp
+	err := component.c.do("POST", "/services/create", nil, spec, &createResp)
+	if err != nil {
+		log.Errorf("Create swarm service[%v] error: %s", spec, err)
+		return swarmResp, errors.New("start service error: " + err.Error())
+	}
+	swarmResp.Service = &types.SwarmService{
+		ID:       createResp.ID,
+		Name:     spec.Name,
+		Warnings: createResp.Warnings,
+	}
+	return swarmResp, nil
+}
+
+func (component *swarmComponent) exists(context ExecutionContext) (bool, error) {
+	swarmResp := new(types.SwarmResp)
+	err := json.Unmarshal([]byte(context.GetKubeResp()), swarmResp)
+	if err != nil {
+		return false, errors.New("unmarshal KubeResp error: " + err.Error())
+	}
+	if swarmResp.Service == nil || swarmResp.Service.ID == "" {
+		return false, nil
+	}
+	err = component.c.do("GET", "/services/"+swarmResp.Service.ID, nil, nil, nil)
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (component *swarmComponent) delete(context ExecutionContext) error {
+	swarmResp := new(types.SwarmResp)
+	err := json.Unmarshal([]byte(context.GetKubeResp()), swarmResp)
+	if err != nil {
+		return errors.New("unmarshal KubeResp error: " + err.Error())
+	}
+	if swarmResp.Service == nil || swarmResp.Service.ID == "" {
+		return nil
+	}
+	err = component.c.do("DELETE", "/services/"+swarmResp.Service.ID, nil, nil, nil)
+	if err != nil {
+		return errors.New("delete service error: " + err.Error())
+	}
+	return nil
+}
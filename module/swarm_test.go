@@ -0,0 +1,157 @@
+package module
+
+import (
+	"encoding/json"
+	"github.com/sosozhuang/component/model"
+	"github.com/sosozhuang/component/types"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newSwarmTestComponent(t *testing.T, host string, seqID int64) *swarmComponent {
+	client, err := newDockerClient(host)
+	if err != nil {
+		t.Fatalf("newDockerClient error: %s", err)
+	}
+	return &swarmComponent{SeqID: seqID, c: client}
+}
+
+func TestSwarmCreate(t *testing.T) {
+	var spec swarmServiceSpec
+	server, requests := fakeAPIServer(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			t.Errorf("decode service spec error: %s", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ID":"svc-1","Warnings":["w"]}`))
+	})
+	defer server.Close()
+
+	component := newSwarmTestComponent(t, server.URL, 7)
+	context := &componentExecutionContext{&model.ComponentExecution{
+		ID:        7,
+		Executor:  model.Executor{Name: "team"},
+		ImageName: "busybox",
+		ImageTag:  "1.0",
+		Timeout:   60,
+		Input:     `{"a":1}`,
+		Envs:      `[{"key":"FOO","value":"bar"}]`,
+		Secret:    "token",
+	}}
+	resp, err := component.create(context)
+	if err != nil {
+		t.Fatalf("create error: %s", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(*requests))
+	}
+	r := (*requests)[0]
+	if r.Method != "POST" || r.URL.Path != "/"+dockerApiVersion+"/services/create" {
+		t.Errorf("got %s %s, want POST /%s/services/create", r.Method, r.URL.Path, dockerApiVersion)
+	}
+	if spec.Name != "co-svc-7" {
+		t.Errorf("got service name %q, want co-svc-7", spec.Name)
+	}
+	if image := spec.TaskTemplate.ContainerSpec.Image; image != "busybox:1.0" {
+		t.Errorf("got image %q, want busybox:1.0", image)
+	}
+
+	env := make(map[string]string)
+	for _, value := range spec.TaskTemplate.ContainerSpec.Env {
+		pair := strings.SplitN(value, "=", 2)
+		if len(pair) == 2 {
+			env[pair[0]] = pair[1]
+		}
+	}
+	wantEnv := map[string]string{
+		"FOO":                "bar",
+		"CO_EXECUTE_SEQ_ID":  "7",
+		"CO_EXECUTE_TIMEOUT": "60",
+		"CO_INPUT":           `{"a":1}`,
+		"CO_EVENT_URL":       ServiceUrl + "/v2/events",
+		eventTokenEnv:        "token",
+	}
+	for key, want := range wantEnv {
+		if got, ok := env[key]; !ok || got != want {
+			t.Errorf("got env %s=%q, want %q", key, got, want)
+		}
+	}
+
+	wantLabels := map[string]string{
+		"CO_EXECUTE_SEQ_ID": "7",
+		"CO_EXECUTOR":       "team",
+		installationLabel:   installationID(),
+	}
+	for key, want := range wantLabels {
+		if got := spec.Labels[key]; got != want {
+			t.Errorf("got service label %s=%q, want %q", key, got, want)
+		}
+		if got := spec.TaskTemplate.ContainerSpec.Labels[key]; got != want {
+			t.Errorf("got container label %s=%q, want %q", key, got, want)
+		}
+	}
+
+	swarmResp, ok := resp.(*types.SwarmResp)
+	if !ok || swarmResp.Service == nil {
+		t.Fatalf("got resp %#v, want a swarm service", resp)
+	}
+	if swarmResp.Service.ID != "svc-1" || swarmResp.Service.Name != "co-svc-7" {
+		t.Errorf("got service %+v, want id svc-1 and name co-svc-7", *swarmResp.Service)
+	}
+}
+
+func TestSwarmCreateError(t *testing.T) {
+	server, _ := fakeAPIServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"message":"no such image"}`))
+	})
+	defer server.Close()
+
+	component := newSwarmTestComponent(t, server.URL, 7)
+	context := &componentExecutionContext{&model.ComponentExecution{ID: 7, ImageName: "busybox"}}
+	if _, err := component.create(context); err == nil {
+		t.Error("create succeeded, want an error")
+	}
+}
+
+func TestSwarmDelete(t *testing.T) {
+	server, requests := fakeAPIServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	component := newSwarmTestComponent(t, server.URL, 7)
+	context := &componentExecutionContext{&model.ComponentExecution{
+		ID:       7,
+		KubeResp: `{"service":{"id":"svc-1","name":"co-svc-7"}}`,
+	}}
+	if err := component.delete(context); err != nil {
+		t.Fatalf("delete error: %s", err)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(*requests))
+	}
+	r := (*requests)[0]
+	if r.Method != "DELETE" || r.URL.Path != "/"+dockerApiVersion+"/services/svc-1" {
+		t.Errorf("got %s %s, want DELETE /%s/services/svc-1", r.Method, r.URL.Path, dockerApiVersion)
+	}
+}
+
+func TestSwarmDeleteWithoutService(t *testing.T) {
+	server, requests := fakeAPIServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	component := newSwarmTestComponent(t, server.URL, 7)
+	context := &componentExecutionContext{&model.ComponentExecution{ID: 7, KubeResp: `{}`}}
+	if err := component.delete(context); err != nil {
+		t.Fatalf("delete error: %s", err)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("got %d requests, want none", len(*requests))
+	}
+}
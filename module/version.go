@@ -0,0 +1,124 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Masterminds/semver"
+	"github.com/sosozhuang/component/model"
+	"regexp"
+	"sort"
+)
+
+// versionPattern is a full MAJOR.MINOR.PATCH semantic version with an
+// optional pre-release and build metadata. semver.NewVersion also accepts a
+// leading v and short forms such as 1.0, which would let 1.0 and 1.0.0 both
+// be saved.
+var versionPattern = regexp.MustCompile(`^(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)` +
+	`(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
+
+// validateVersion checks a component version is a semantic version such
+// as 1.2.0.
+func validateVersion(version string) error {
+	if !versionPattern.MatchString(version) {
+		return errors.New("invalid component version " + version + ", should be a semantic version such as 1.2.0")
+	}
+	if _, err := semver.NewVersion(version); err != nil {
+		return errors.New("invalid component version " + version + ", should be a semantic version: " + err.Error())
+	}
+	return nil
+}
+
+// checkVersionUnique returns an error if component name already has a
+// version equal to version, build metadata aside, so constraints always
+// resolve to a single version.
+func checkVersionUnique(name, version string) error {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return errors.New("invalid component version " + version + ": " + err.Error())
+	}
+	components, err := model.SelectComponentVersions(name)
+	if err != nil {
+		return errors.New("query component versions error: " + err.Error())
+	}
+	if component := equalVersion(components, v); component != nil {
+		return fmt.Errorf("component exists as version %s, id is: %d", component.Version, component.ID)
+	}
+	return nil
+}
+
+// equalVersion returns the version of a component equal to v, nil if none
+// is.
+func equalVersion(components []model.Component, v *semver.Version) *model.Component {
+	for i := range components {
+		existing, err := semver.NewVersion(components[i].Version)
+		if err == nil && existing.Equal(v) {
+			return &components[i]
+		}
+	}
+	return nil
+}
+
+// versionLess orders semantic versions semantically, before the versions
+// saved by older releases which are not semantic and are ordered lexically.
+func versionLess(a, b string) bool {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	switch {
+	case errA == nil && errB == nil:
+		if va.Equal(vb) {
+			return a < b
+		}
+		return va.LessThan(vb)
+	case errA == nil:
+		return true
+	case errB == nil:
+		return false
+	default:
+		return a < b
+	}
+}
+
+type componentsByVersion []model.Component
+
+func (c componentsByVersion) Len() int      { return len(c) }
+func (c componentsByVersion) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c componentsByVersion) Less(i, j int) bool {
+	if c[i].Name != c[j].Name {
+		return c[i].Name < c[j].Name
+	}
+	return versionLess(c[i].Version, c[j].Version)
+}
+
+// ResolveComponentVersion returns the newest version of component name
+// matching constraint, such as ^1.2 or >=1.0, <2.0, any released version
+// matches an empty constraint.
+func ResolveComponentVersion(name, constraint string) (*model.Component, error) {
+	if name == "" {
+		return nil, errors.New("should specify component name")
+	}
+	if constraint == "" {
+		constraint = "*"
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, errors.New("parse version constraint error: " + err.Error())
+	}
+	components, err := model.SelectComponentVersions(name)
+	if err != nil {
+		return nil, errors.New("query component versions error: " + err.Error())
+	}
+	return newestMatching(components, c), nil
+}
+
+// newestMatching returns the newest of the versions of a component which
+// matches c, nil if none does.
+func newestMatching(components []model.Component, c *semver.Constraints) *model.Component {
+	sort.Sort(sort.Reverse(componentsByVersion(components)))
+	for i := range components {
+		v, err := semver.NewVersion(components[i].Version)
+		if err == nil && c.Check(v) {
+			return &components[i]
+		}
+	}
+	return nil
+}
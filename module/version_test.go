@@ -0,0 +1,169 @@
+package module
+
+import (
+	"github.com/Masterminds/semver"
+	"github.com/sosozhuang/component/model"
+	"reflect"
+	"testing"
+)
+
+func TestValidateVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		valid   bool
+	}{
+		{"1.2.3", true},
+		{"0.10.0", true},
+		{"1.0.0-rc.1", true},
+		{"1.0.0+build.5", true},
+		{"v1.0.0", false},
+		{"v1.0", false},
+		{"1.0", false},
+		{"1", false},
+		{"01.0.0", false},
+		{"1.0.0-", false},
+		{"", false},
+		{"latest", false},
+		{"1.2.3.4", false},
+		{"1.x.0", false},
+	}
+	for _, test := range tests {
+		err := validateVersion(test.version)
+		if (err == nil) != test.valid {
+			t.Errorf("validateVersion(%q) got error %v, want valid %v", test.version, err, test.valid)
+		}
+	}
+}
+
+func TestVersionLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		less bool
+	}{
+		{"1.9.0", "1.10.0", true},
+		{"1.10.0", "1.9.0", false},
+		{"1.9", "1.10", true},
+		{"2.0.0", "10.0.0", true},
+		{"1.0.0-beta", "1.0.0", true},
+		{"1.0.0", "1.0.0-beta", false},
+		{"v1.2.0", "1.3.0", true},
+		{"1.2.0", "1.2.0", false},
+		// Equal versions written differently are ordered lexically.
+		{"1.0", "1.0.0", true},
+		{"1.0.0", "1.0", false},
+		// Versions which are not semantic sort last, lexically.
+		{"10.0.0", "latest", true},
+		{"latest", "1.0.0", false},
+		{"beta", "latest", true},
+		{"latest", "beta", false},
+	}
+	for _, test := range tests {
+		if got := versionLess(test.a, test.b); got != test.less {
+			t.Errorf("versionLess(%q, %q) got %v, want %v", test.a, test.b, got, test.less)
+		}
+	}
+}
+
+func TestEqualVersion(t *testing.T) {
+	components := componentVersions("a", "latest", "1.0", "1.2.0", "2.0.0-rc.1")
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"1.0.0", "1.0"},
+		{"1.2.0+build.5", "1.2.0"},
+		{"2.0.0-rc.1", "2.0.0-rc.1"},
+		{"2.0.0", ""},
+		{"1.2.1", ""},
+	}
+	for _, test := range tests {
+		v, err := semver.NewVersion(test.version)
+		if err != nil {
+			t.Fatalf("NewVersion(%q) error: %s", test.version, err)
+		}
+		var got string
+		if component := equalVersion(components, v); component != nil {
+			got = component.Version
+		}
+		if got != test.want {
+			t.Errorf("version %q got equal version %q, want %q", test.version, got, test.want)
+		}
+	}
+}
+
+func componentVersions(name string, versions ...string) []model.Component {
+	components := make([]model.Component, 0, len(versions))
+	for _, version := range versions {
+		components = append(components, model.Component{Name: name, Version: version})
+	}
+	return components
+}
+
+func names(components []model.Component) []string {
+	result := make([]string, 0, len(components))
+	for _, component := range components {
+		result = append(result, component.Name+"@"+component.Version)
+	}
+	return result
+}
+
+func TestPageComponents(t *testing.T) {
+	all := func() []model.Component {
+		components := componentVersions("b", "latest", "1.10.0", "1.9.0")
+		return append(components, componentVersions("a", "2.0.0", "0.1.0", "1.0.0")...)
+	}
+	tests := []struct {
+		name       string
+		components []model.Component
+		byName     bool
+		versionNum int
+		offset     int
+		want       []string
+	}{
+		{"names", all(), false, 10, 0,
+			[]string{"a@0.1.0", "a@1.0.0", "a@2.0.0", "b@1.9.0", "b@1.10.0", "b@latest"}},
+		{"versions per name", all(), false, 2, 0,
+			[]string{"a@0.1.0", "a@1.0.0", "b@1.9.0", "b@1.10.0"}},
+		{"versions of a name", componentVersions("b", "latest", "1.10.0", "1.9.0", "1.2.0"), true, 2, 1,
+			[]string{"b@1.9.0", "b@1.10.0"}},
+		{"offset past the end", componentVersions("b", "1.0.0"), true, 2, 5,
+			[]string{}},
+	}
+	for _, test := range tests {
+		got := names(pageComponents(test.components, test.byName, test.versionNum, test.offset))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNewestMatching(t *testing.T) {
+	tests := []struct {
+		constraint string
+		want       string
+	}{
+		{"*", "2.0.0"},
+		{"^1.2", "1.10.0"},
+		{"^1", "1.10.0"},
+		{"~1.9", "1.9.3"},
+		{"1.2.0", "1.2.0"},
+		{">=1.0, <1.9", "1.2.0"},
+		{"^0.9", "0.9.0"},
+		{"^3", ""},
+	}
+	for _, test := range tests {
+		c, err := semver.NewConstraint(test.constraint)
+		if err != nil {
+			t.Fatalf("NewConstraint(%q) error: %s", test.constraint, err)
+		}
+		components := componentVersions("a", "1.2.0", "1.10.0", "latest", "1.9.3", "2.0.0", "0.9.0")
+		component := newestMatching(components, c)
+		var got string
+		if component != nil {
+			got = component.Version
+		}
+		if got != test.want {
+			t.Errorf("constraint %q got version %q, want %q", test.constraint, got, test.want)
+		}
+	}
+}
@@ -2,44 +2,70 @@ package router
 
 import (
 	"gopkg.in/macaron.v1"
+	"github.com/sosozhuang/component/auth"
 	"github.com/sosozhuang/component/handler"
+	"github.com/sosozhuang/component/middleware"
 )
 
 func SetRouters(m *macaron.Macaron) {
+	viewer := middleware.RequireRole(auth.RoleViewer)
+	author := middleware.RequireRole(auth.RoleAuthor)
+	executor := middleware.RequireRole(auth.RoleExecutor)
+	admin := middleware.RequireRole(auth.RoleAdmin)
+
 	m.Group("/v2", func() {
 		m.Get("/", handler.IndexHandler)
 
 		//todo: remove begin
-		m.Post("/test", handler.TestHandler)
+		m.Post("/test", admin, handler.TestHandler)
 		//todo: remove end
 
+		// Events are sent by component containers and verified by the
+		// signature of the execution.
 		m.Group("/events", func() {
 			m.Post("/", handler.CreateEvent)
 		})
 
 		m.Group("/components", func() {
-			m.Get("/", handler.ListComponents)
-			m.Post("/", handler.CreateComponent)
+			m.Get("/", viewer, handler.ListComponents)
+			m.Post("/", author, handler.CreateComponent)
 
-			m.Post("/:component", handler.SaveComponentAsNewVersion)
-			m.Get("/:component", handler.GetComponent)
-			m.Put("/:component", handler.UpdateComponent)
-			m.Delete("/:component", handler.DeleteComponent)
+			m.Post("/:component", author, handler.SaveComponentAsNewVersion)
+			m.Get("/:component", viewer, handler.GetComponent)
+			m.Put("/:component", author, handler.UpdateComponent)
+			m.Delete("/:component", author, handler.DeleteComponent)
 
-			m.Get("/:component/debug", handler.DebugComponentJson(), handler.DebugComponent)
-			m.Post("/:component/execute", handler.StartComponent)
+			m.Get("/:component/debug", author, handler.DebugComponentJson(), handler.DebugComponent)
+			m.Post("/:component/execute", executor, handler.StartComponent)
+			m.Post("/by-name/:name/execute", executor, handler.StartComponentByName)
 		})
 
 		m.Group("/executions", func() {
-			m.Get("/:execution", handler.GetComponentExecution)
-			m.Delete("/:execution", handler.StopComponentExecution)
+			m.Get("/", viewer, handler.ListComponentExecutions)
+			m.Get("/:execution", viewer, handler.GetComponentExecution)
+			m.Delete("/:execution", executor, handler.StopComponentExecution)
+			m.Get("/:execution/logs", viewer, handler.GetComponentExecutionLogs)
+			m.Get("/:execution/stream", viewer, handler.StreamComponentExecution)
+			m.Get("/:execution/output", viewer, handler.GetComponentExecutionOutput)
+			m.Get("/:execution/notifications", viewer, handler.ListExecutionNotifications)
+			m.Post("/:execution/notifications/replay", executor, handler.ReplayExecutionNotifications)
+			m.Post("/:execution/notifications/:notification/replay", executor, handler.ReplayExecutionNotifications)
 		})
 
+		m.Group("/executors", func() {
+			m.Get("/", handler.ListExecutors)
+			m.Post("/", handler.RegisterExecutor)
+
+			m.Get("/:executor", handler.GetExecutor)
+			m.Delete("/:executor", handler.RevokeExecutor)
+			m.Post("/:executor/rotate", handler.RotateExecutorKey)
+		}, admin)
+
 		m.Group("/images", func() {
-			m.Post("/check", handler.CheckImageScript)
+			m.Post("/check", author, handler.CheckImageScript)
 			//todo: remove begin
-			m.Post("/build", handler.BuildImage)
-			m.Post("/test", handler.TestHandler)
+			m.Post("/build", author, handler.BuildImage)
+			m.Post("/test", admin, handler.TestHandler)
 			//todo: remove end
 		})
 	})
@@ -3,7 +3,10 @@ package types
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 	"k8s.io/client-go/pkg/api/v1"
+	batchv1 "k8s.io/client-go/pkg/apis/batch/v1"
+	"strconv"
 )
 
 type ComponentType string
@@ -53,6 +56,16 @@ func (status ExecutionStatus) String() string {
 	}
 }
 
+// ParseExecutionStatus accepts the name or the number of a status.
+func ParseExecutionStatus(s string) (ExecutionStatus, error) {
+	for status := ComponentExecutionStatusAccepted; status <= ComponentExecutionStatusFailed; status++ {
+		if s == status.String() || s == strconv.Itoa(int(status)) {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid execution status: %s", s)
+}
+
 type ExecuteComponentMsg struct {
 	ExecuteSeqID int64            `json:"execute_seq_id"`
 	ComponentID  int64            `json:"component_id"`
@@ -68,6 +81,7 @@ type ExecuteComponentMsg struct {
 	NotifyUrl    `json:"notify_url"`
 	KubeResp     *json.RawMessage `json:"kube_resp"`
 	Detail       string           `json:"detail"`
+	Output       *json.RawMessage `json:"output,omitempty"`
 	Events       []EventMsg       `json:"events"`
 }
 
@@ -79,12 +93,86 @@ type NotifyUrl struct {
 }
 
 type KubeSetting struct {
-	Pod     *v1.Pod     `json:"pod,omitempty"`
-	Service *v1.Service `json:"service,omitempty"`
+	Pod     *v1.Pod         `json:"pod,omitempty"`
+	Service *v1.Service     `json:"service,omitempty"`
+	Job     *JobSetting     `json:"job,omitempty"`
+	Process *ProcessSetting `json:"process,omitempty"`
+}
+
+// JobSetting runs the pod of a kubernetes component as a batch/v1 job.
+type JobSetting struct {
+	BackoffLimit int32 `json:"backoff_limit"`
+}
+
+type KubeResp struct {
+	Pod     *v1.Pod      `json:"pod,omitempty"`
+	Service *v1.Service  `json:"service,omitempty"`
+	Job     *batchv1.Job `json:"job,omitempty"`
+	// Secret holds the event token of the execution, only its name and
+	// namespace are kept.
+	Secret *v1.Secret `json:"secret,omitempty"`
+}
+
+// ProcessSetting describes a script only component, which a local
+// component runs as a host process instead of a container.
+type ProcessSetting struct {
+	Command []string `json:"command"`
+	Dir     string   `json:"dir,omitempty"`
+}
+
+// LogOptions selects the output of an execution. An empty Container means
+// the first container when following and every container otherwise.
+type LogOptions struct {
+	Container    string
+	Follow       bool
+	TailLines    *int64
+	SinceSeconds *int64
+}
+
+type SwarmResp struct {
+	Service *SwarmService `json:"service,omitempty"`
+}
+
+type SwarmService struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type MesosResp struct {
+	App *MarathonApp `json:"app,omitempty"`
+}
+
+type MarathonApp struct {
+	ID          string   `json:"id"`
+	Version     string   `json:"version"`
+	Deployments []string `json:"deployments,omitempty"`
+}
+
+type LocalResp struct {
+	Container *LocalContainer `json:"container,omitempty"`
+	Process   *LocalProcess   `json:"process,omitempty"`
+}
+
+type LocalContainer struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type LocalProcess struct {
+	Pid int `json:"pid"`
 }
 
 func (e *EventType) Scan(value interface{}) error {
-	*e = EventType(value.([]byte))
+	switch v := value.(type) {
+	case []byte:
+		*e = EventType(v)
+	case string:
+		*e = EventType(v)
+	default:
+		return fmt.Errorf("can't scan %T into EventType", value)
+	}
 	return nil
 }
 
@@ -5,6 +5,7 @@ import "time"
 type EventMsg struct {
 	//Nounce       string
 	//Sign         string
+	ID           int64     `json:"id,omitempty"`
 	ExecuteSeqID int64     `json:"execute_seq_id"`
 	//ExecutorID   string    `json:"executor_id"`
 	Type         EventType `json:"type"`